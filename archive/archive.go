@@ -9,7 +9,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
-	"path/filepath"
+	"strings"
 )
 
 // ErrDone 是一个用于标记处理完成的错误标识
@@ -40,10 +40,10 @@ type ProcessFunc func(ctx context.Context, item Item) (err error)
 // 返回值:
 //   - 处理过程中发生的错误，如果正常结束或遇到EOF、SkipAll、ErrDone则返回nil
 func Read(ctx context.Context, source string, process ProcessFunc) (err error) {
-	switch filepath.Ext(source) {
-	case ".zip":
+	switch {
+	case strings.HasSuffix(source, ".zip"):
 		err = readZip(ctx, source, process)
-	case ".tar.gz":
+	case strings.HasSuffix(source, ".tar.gz"):
 		err = readTgz(ctx, source, process)
 	}
 
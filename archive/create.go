@@ -0,0 +1,289 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cnk3x/pkg/filex"
+)
+
+// Prefix 设置打包时归档内路径的前缀目录
+//
+// 参数:
+//   - prefix: 前缀目录，如 "app-1.0"，打包后的路径为 app-1.0/...
+//
+// 返回值:
+//   - Option: 选项函数
+func Prefix(prefix string) Option {
+	return func(option *options) { option.prefix = strings.Trim(filepath.ToSlash(prefix), "/") }
+}
+
+// Exclude 设置打包时排除的路径，匹配任意一个即排除，排除的目录不再遍历
+//
+// 参数:
+//   - excludes: 正则表达式字符串列表，匹配相对于源目录的路径(以 / 分隔)
+//
+// 返回值:
+//   - Option: 选项函数
+func Exclude(excludes ...string) Option { return func(option *options) { option.excludes = excludes } }
+
+// ModTime 设置打包时所有项目的修改时间，用于生成可重复的归档文件，默认保留文件原有的修改时间
+//
+// 参数:
+//   - t: 修改时间
+//
+// 返回值:
+//   - Option: 选项函数
+func ModTime(t time.Time) Option { return func(option *options) { option.modTime = t } }
+
+// Create 将目录中的内容打包为归档文件，根据文件扩展名选择格式，支持 .zip 和 .tar.gz 格式
+//
+//   - 按路径顺序打包，配合 ModTime 可以生成可重复的归档文件
+//   - 保留文件的权限和修改时间，符号链接保存为链接本身
+//   - Filter 对文件和符号链接生效，目录只在匹配时写入目录项，Exclude 对所有项目生效
+//
+// 参数:
+//   - ctx: 上下文，用于控制处理流程和超时取消
+//   - dst: 归档文件的路径，先写入临时文件，成功后再替换，位于 srcDir 中时不会打包自身
+//   - srcDir: 要打包的目录，归档中的路径相对于此目录
+//   - createOptions: 打包选项，支持 Filter、Exclude、Prefix、ModTime、Progress
+//
+// 返回值:
+//   - 处理过程中发生的错误
+func Create(ctx context.Context, dst, srcDir string, createOptions ...Option) (err error) {
+	var cop options
+	for _, o := range createOptions {
+		o(&cop)
+	}
+
+	var newWriter func(w io.Writer) archiveWriter
+	switch {
+	case strings.HasSuffix(dst, ".zip"):
+		newWriter = newZipWriter
+	case strings.HasSuffix(dst, ".tar.gz"):
+		newWriter = newTgzWriter
+	default:
+		return fmt.Errorf("archive: unsupported format: %s", dst)
+	}
+
+	filters, err := compileAll(cop.filters)
+	if err != nil {
+		return
+	}
+	excludes, err := compileAll(cop.excludes)
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	//dst 在 srcDir 中时，跳过临时文件和旧的归档文件
+	skips := map[string]bool{}
+	for _, name := range []string{dst, tmp.Name()} {
+		if abs, e := filepath.Abs(name); e == nil {
+			skips[abs] = true
+		}
+	}
+	absSrc, err := filepath.Abs(srcDir)
+	if err != nil {
+		return
+	}
+
+	aw := newWriter(tmp)
+	index := 0
+	if err = filepath.WalkDir(srcDir, func(fullPath string, d fs.DirEntry, err error) error {
+		if err != nil || fullPath == srcDir {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, fullPath)
+		if err != nil {
+			return err
+		}
+		if skips[filepath.Join(absSrc, rel)] {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(excludes, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if len(filters) > 0 && !matchAll(filters, rel) {
+			return nil //不匹配的目录继续遍历其中的文件
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		name := rel
+		if cop.prefix != "" {
+			name = path.Join(cop.prefix, rel)
+		}
+
+		mtime := info.ModTime()
+		if !cop.modTime.IsZero() {
+			mtime = cop.modTime
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(fullPath); err != nil {
+				return err
+			}
+		}
+
+		if err = aw.WriteHeader(name, info, mtime, filepath.ToSlash(link)); err != nil {
+			return err
+		}
+
+		i := index
+		if index++; !info.Mode().IsRegular() {
+			return nil
+		}
+
+		var p filex.ProgressFunc
+		if cop.progress != nil {
+			current, total := int64(0), info.Size()
+			p = func(n int64) { cop.progress(i, name, atomic.AddInt64(&current, n), total) }
+		}
+		return filex.OpenRead(fullPath, filex.ReadTo(ctx, aw, p))
+	}); err != nil {
+		return
+	}
+
+	if err = aw.Close(); err != nil {
+		return
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// archiveWriter 归档文件的写入，WriteHeader 之后写入文件内容
+type archiveWriter interface {
+	io.WriteCloser
+	WriteHeader(name string, info fs.FileInfo, mtime time.Time, link string) error
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+	w  io.Writer
+}
+
+func newZipWriter(w io.Writer) archiveWriter { return &zipWriter{zw: zip.NewWriter(w)} }
+
+func (z *zipWriter) WriteHeader(name string, info fs.FileInfo, mtime time.Time, link string) (err error) {
+	h, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return
+	}
+	h.Name, h.Modified = name, mtime
+	if info.IsDir() {
+		h.Name += "/"
+	}
+	if info.Mode().IsRegular() {
+		h.Method = zip.Deflate
+	}
+
+	if z.w, err = z.zw.CreateHeader(h); err == nil && link != "" {
+		_, err = io.WriteString(z.w, link) //zip 中符号链接的内容为链接目标
+	}
+	return
+}
+
+func (z *zipWriter) Write(p []byte) (int, error) { return z.w.Write(p) }
+func (z *zipWriter) Close() error                { return z.zw.Close() }
+
+type tgzWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTgzWriter(w io.Writer) archiveWriter {
+	gw := gzip.NewWriter(w)
+	return &tgzWriter{gw: gw, tw: tar.NewWriter(gw)}
+}
+
+func (t *tgzWriter) WriteHeader(name string, info fs.FileInfo, mtime time.Time, link string) (err error) {
+	h, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return
+	}
+	h.Name, h.ModTime, h.AccessTime, h.ChangeTime = name, mtime, time.Time{}, time.Time{}
+	if info.IsDir() {
+		h.Name += "/"
+	}
+	return t.tw.WriteHeader(h)
+}
+
+func (t *tgzWriter) Write(p []byte) (int, error) { return t.tw.Write(p) }
+
+func (t *tgzWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gw.Close()
+}
+
+func compileAll(patterns []string) (res []*regexp.Regexp, err error) {
+	for _, p := range patterns {
+		re, e := regexp.Compile(p)
+		if e != nil {
+			return nil, e
+		}
+		res = append(res, re)
+	}
+	return
+}
+
+func matchAll(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if !re.MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
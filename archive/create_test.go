@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{"bin/run.sh": "#!/bin/sh", "a.txt": "hello", "debug.log": "x"} {
+		if err := os.MkdirAll(filepath.Join(src, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(src, "bin/run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		var progress []string
+		create := func(name string) []byte {
+			file := filepath.Join(dst, name+ext)
+			err := Create(context.Background(), file, src, Prefix("app"), Exclude(`\.log$`), ModTime(mtime),
+				Progress(func(index int, name string, cur, total int64) {
+					if cur == total {
+						progress = append(progress, name)
+					}
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := os.ReadFile(file)
+			return data
+		}
+
+		if !bytes.Equal(create("a"), create("b")) {
+			t.Errorf("%s: not reproducible", ext)
+		}
+		if want := []string{"app/a.txt", "app/bin/run.sh"}; !slices.Equal(progress[:2], want) {
+			t.Errorf("%s: progress %v, want %v", ext, progress, want)
+		}
+
+		var names []string
+		err := Read(context.Background(), filepath.Join(dst, "a"+ext), func(ctx context.Context, item Item) error {
+			names = append(names, item.Path())
+			if !item.ModTime().Equal(mtime) {
+				t.Errorf("%s: %s mtime %v", ext, item.Path(), item.ModTime())
+			}
+			switch item.Path() {
+			case "app/bin/run.sh":
+				if item.Mode().Perm() != 0755 {
+					t.Errorf("%s: %s mode %v", ext, item.Path(), item.Mode())
+				}
+			case "app/link":
+				if item.Mode()&fs.ModeSymlink == 0 {
+					t.Errorf("%s: %s is not symlink: %v", ext, item.Path(), item.Mode())
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"app/a.txt", "app/bin/", "app/bin/run.sh", "app/link"}; !slices.Equal(names, want) {
+			t.Errorf("%s: got %v, want %v", ext, names, want)
+		}
+	}
+}
+
+// 归档文件位于源目录中时，不打包临时文件和旧的归档文件
+func TestCreateInside(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		src := t.TempDir()
+		if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		file := filepath.Join(src, "out"+ext)
+		for range 2 { //第二次时旧的归档文件已存在
+			if err := Create(context.Background(), file, src); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var names []string
+		err := Read(context.Background(), file, func(ctx context.Context, item Item) error {
+			names = append(names, item.Path())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(names, "a.txt") || slices.ContainsFunc(names, func(name string) bool { return strings.Contains(name, "out") }) {
+			t.Errorf("%s: got %v", ext, names)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/cnk3x/pkg/filex"
 )
@@ -16,6 +17,10 @@ type options struct {
 	skipEmptyDir    bool
 	progress        func(index int, name string, cur, total int64)
 	filters         []string
+
+	prefix   string    //打包
+	excludes []string  //打包
+	modTime  time.Time //打包
 }
 
 const pathSeparator = string(filepath.Separator)
@@ -93,7 +98,7 @@ func Extract(dir string, extractOptions ...Option) ProcessFunc {
 	}
 }
 
-// Option 定义了解压选项的函数类型
+// Option 定义了解压和打包选项的函数类型
 type Option func(option *options)
 
 // Filter 设置文件路径过滤器
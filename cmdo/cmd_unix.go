@@ -15,3 +15,22 @@ func setPKill(c *exec.Cmd) {
 func terminate(proc *os.Process) error {
 	return syscall.Kill(int(-proc.Pid), syscall.SIGTERM)
 }
+
+func signal(proc *os.Process, sig os.Signal) error {
+	if s, ok := sig.(syscall.Signal); ok {
+		if err := syscall.Kill(int(-proc.Pid), s); err == nil {
+			return nil
+		}
+	}
+	return proc.Signal(sig)
+}
+
+var signals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
@@ -15,3 +15,20 @@ func setPKill(c *exec.Cmd) {
 func terminate(proc *os.Process) error {
 	return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(int(proc.Pid))).Run()
 }
+
+func signal(proc *os.Process, sig os.Signal) error {
+	switch sig {
+	case os.Kill, syscall.SIGTERM:
+		return terminate(proc)
+	default:
+		return proc.Signal(sig)
+	}
+}
+
+var signals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
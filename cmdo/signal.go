@@ -0,0 +1,36 @@
+package cmdo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ParseSignal 解析信号名称，支持 SIGTERM、TERM、term 以及数字形式
+func ParseSignal(name string) (os.Signal, error) {
+	s := strings.ToUpper(strings.TrimSpace(name))
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signals[strings.TrimPrefix(s, "SIG")]; ok {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unknown signal: %s", name)
+}
+
+// Signal 向进程(组)发送信号，进程需使用 PKill 启动
+//
+// 参数:
+//   - proc: 目标进程
+//   - sig: 要发送的信号
+//
+// 返回值:
+//   - error: 发送失败时返回错误
+func Signal(proc *os.Process, sig os.Signal) error {
+	if proc == nil {
+		return os.ErrProcessDone
+	}
+	return signal(proc, sig)
+}
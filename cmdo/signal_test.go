@@ -0,0 +1,53 @@
+package cmdo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		want os.Signal
+	}{
+		{"TERM", syscall.SIGTERM},
+		{"SIGTERM", syscall.SIGTERM},
+		{"term", syscall.SIGTERM},
+		{" sighup ", syscall.SIGHUP},
+		{"KILL", syscall.SIGKILL},
+		{"9", syscall.Signal(9)},
+	} {
+		if sig, err := ParseSignal(c.name); err != nil || sig != c.want {
+			t.Errorf("%q: 期望 %v，实际 %v, err=%v", c.name, c.want, sig, err)
+		}
+	}
+
+	for _, name := range []string{"", "0", "-1", "FOO", "SIG"} {
+		if sig, err := ParseSignal(name); err == nil {
+			t.Errorf("%q: 期望返回错误，实际 %v", name, sig)
+		}
+	}
+}
+
+func TestSignal(t *testing.T) {
+	if err := Signal(nil, syscall.SIGTERM); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("进程为空: %v", err)
+	}
+
+	c := Apply(exec.CommandContext(t.Context(), "sleep", "30"), PKill)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Process.Kill()
+
+	if err := Signal(c.Process, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	c.Wait()
+	if ws, ok := c.ProcessState.Sys().(syscall.WaitStatus); !ok || !ws.Signaled() || ws.Signal() != syscall.SIGTERM {
+		t.Errorf("进程未被 TERM 终止: %s", c.ProcessState)
+	}
+}
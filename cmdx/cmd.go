@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,6 +23,8 @@ const (
 	statusRunning    = "running"    //正在运行
 	statusRestarting = "restarting" //正在重启
 	statusStopped    = "stopped"    //已经停止
+	statusUnhealthy  = "unhealthy"  //健康检查失败
+	statusFailed     = "failed"     //连续重启次数超过限制
 )
 
 type Config struct {
@@ -32,7 +35,22 @@ type Config struct {
 	Dir        string         `json:"dir,omitempty"`
 	Log        LogConfig      `json:"log"`
 	Restart    RestartConfig  `json:"restart"`
+	Health     HealthConfig   `json:"health,omitzero"`
 	WaitDelay  jsonx.Duration `json:"wait_delay,omitempty"`
+
+	StopSignal   string        `json:"stop_signal,omitempty"`   //停止信号，默认 SIGTERM
+	StopSequence jsonx.Strings `json:"stop_sequence,omitempty"` //停止序列，如 ["TERM", "10s", "KILL"]，配置后忽略 StopSignal 和 WaitDelay
+
+	DependsOn jsonx.Strings `json:"depends_on,omitempty"` //依赖的程序名称，仅在 Supervisor 中生效
+
+	User    string            `json:"user,omitempty"`    //运行用户，名称或uid
+	Group   string            `json:"group,omitempty"`   //运行用户组，名称或gid，默认为用户的主组
+	Nice    int               `json:"nice,omitempty"`    //进程优先级(-20~19)，linux 下在 exec 之前生效，其他系统在启动后设置
+	Umask   string            `json:"umask,omitempty"`   //文件创建掩码(八进制)，如 "027"，linux 下只对子进程生效，其他系统启动期间临时修改当前进程的 umask
+	Rlimits map[string]uint64 `json:"rlimits,omitempty"` //资源限制(仅linux)，如 {"nofile": 65535}，支持 nofile, nproc, as(memory), cpu, core, fsize, stack, data, memlock，通过 prlimit 命令在 exec 之前生效，没有 prlimit 命令时在启动后设置
+	Cgroup  CgroupConfig      `json:"cgroup,omitzero"`   //cgroup v2 限制(仅linux)
+
+	OutputLines int `json:"output_lines,omitempty"` //内存中保留最近多少行输出，默认1000，小于0不保留
 }
 
 type Program struct {
@@ -44,8 +62,16 @@ type Program struct {
 	stop    context.CancelFunc
 	restart context.CancelFunc
 
-	status string
-	done   <-chan struct{}
+	restartAsked atomic.Bool //由 Restart 请求的退出，不计入重启次数
+
+	status   string
+	lastErr  error
+	lastExit *ExitInfo
+	proc     *os.Process
+	events   hub[Event]
+	output   outputBuffer
+	done     <-chan struct{}
+	mu       sync.Mutex
 }
 
 // Start 启动一个程序并返回Program实例
@@ -63,11 +89,20 @@ func Start(ctx context.Context, options ...Option) *Program {
 	for _, option := range options {
 		option.apply(s)
 	}
+	s.output.init(s.cfg.OutputLines)
 
 	//方法：上报状态变更
-	statusUp := func(status string) { s.status = status }
+	statusUp := func(status string) {
+		s.mu.Lock()
+		changed := s.status != status
+		s.status = status
+		s.mu.Unlock()
+		if changed {
+			s.emit(Event{Type: EventStatus, Status: status})
+		}
+	}
 	//方法：状态判断
-	statusIs := func(status string) bool { return s.status == status }
+	statusIs := func(status string) bool { return s.Status() == status }
 
 	//方法：主体执行
 	directRun := func(stop_ctx context.Context) (err error) {
@@ -75,13 +110,12 @@ func Start(ctx context.Context, options ...Option) *Program {
 			return errx.Errorf("cmdx: already running")
 		}
 
-		if s.restart != nil {
-			s.restart()
-		}
-
 		restart_ctx, cancel := context.WithCancel(stop_ctx)
 		defer cancel()
-		s.restart = cancel
+		if prev := s.swap(&s.restart, cancel); prev != nil {
+			prev()
+		}
+		s.restartAsked.Store(false)
 
 		if !statusIs(statusRestarting) {
 			statusUp(statusStarting)
@@ -100,9 +134,21 @@ func Start(ctx context.Context, options ...Option) *Program {
 		}
 
 		x := s.cfg
+		steps, err := x.stopSteps()
+		if err != nil {
+			return
+		}
+
+		exited := make(chan struct{})
+		defer close(exited)
+
 		c := exec.CommandContext(restart_ctx, x.Path, x.Args...)
 		c.SysProcAttr = &syscall.SysProcAttr{}
 		cmdo.PKill(c)
+		c.Cancel = func() error {
+			go runStopSteps(s.log, c.Process, steps, exited)
+			return nil
+		}
 		c.Dir = x.Dir
 
 		if x.InheritEnv {
@@ -110,13 +156,16 @@ func Start(ctx context.Context, options ...Option) *Program {
 		}
 		c.Env = append(c.Env, x.Env...)
 
-		c.WaitDelay = max(x.WaitDelay.Value(), time.Second*5) //调用cancel后等待退出，最低5s
+		c.WaitDelay = stopWait(steps) + time.Second*2 //调用cancel后等待停止序列执行完毕
 
 		l0, l1, lc, le := x.Log.Open()
 		if err = le; err != nil {
 			return errx.Errorf("cmdx: %w", err)
 		}
-		c.Stdout, c.Stderr = l0, l1
+		//无论日志输出到哪里，都保留最近的输出
+		var flushOut, flushErr func()
+		c.Stdout, flushOut = s.output.writer(StreamStdout, l0)
+		c.Stderr, flushErr = s.output.writer(StreamStderr, l1)
 		defer lc()
 
 		if c.Dir != "" {
@@ -125,14 +174,51 @@ func Start(ctx context.Context, options ...Option) *Program {
 			}
 		}
 
+		start, cleanup, err := x.procPrepare(c, s.log)
+		if err != nil {
+			return
+		}
+		defer cleanup()
+
 		s.log.Debug("启动", "cmdline", c.String())
-		if err = c.Start(); err != nil {
+		if err = start(); err != nil {
 			return errx.Errorf("cmdx: %w", err)
 		}
-		s.log.Debug("已启动", "pid", c.Process.Pid)
+		pid, startAt := c.Process.Pid, time.Now()
+		s.setProc(c.Process)
+		defer s.setProc(nil)
+		s.log.Debug("已启动", "pid", pid)
+		s.emit(Event{Type: EventStarted, Pid: pid})
 
 		statusUp(statusRunning)
-		if err = c.Wait(); err != nil {
+
+		//健康检查失败后，重启策略会重启时取消本次运行，交由重启流程处理，否则只报告不健康，恢复后回到运行状态
+		go x.Health.Watch(restart_ctx, s.log, func(healthy bool, err error) {
+			if healthy {
+				s.emit(Event{Type: EventHealth, Pid: pid, Health: "healthy"})
+				if statusIs(statusUnhealthy) {
+					statusUp(statusRunning)
+				}
+				return
+			}
+			s.log.Warn("健康检查失败", "err", err)
+			s.emit(Event{Type: EventHealth, Pid: pid, Health: "unhealthy", Err: err.Error()})
+			statusUp(statusUnhealthy)
+			if x.Restart.ShouldRestart(stop_ctx, err) {
+				cancel()
+			}
+		})
+
+		err = c.Wait()
+		flushOut()
+		flushErr()
+		exit := newExitInfo(pid, startAt, c.ProcessState, err)
+		s.mu.Lock()
+		s.lastExit = exit
+		s.mu.Unlock()
+		s.emit(Event{Type: EventExited, Pid: pid, Exit: exit})
+
+		if err != nil {
 			return errx.Errorf("cmdx: %w", err)
 		}
 		return
@@ -140,12 +226,10 @@ func Start(ctx context.Context, options ...Option) *Program {
 
 	//方法: 运行
 	run := func(ctx context.Context) {
-		if s.stop != nil {
-			s.stop()
-		}
-
 		stop_ctx, cancel := context.WithCancel(ctx)
-		s.stop = cancel
+		if prev := s.swap(&s.stop, cancel); prev != nil {
+			prev()
+		}
 
 		for count := 1; ; count++ {
 			select {
@@ -156,12 +240,42 @@ func Start(ctx context.Context, options ...Option) *Program {
 			default:
 			}
 
+			startAt := time.Now()
 			err := directRun(stop_ctx)
 			if err != nil {
 				s.log.Debug("运行结果", "err", err.Error())
 			}
 
-			restart := s.cfg.Restart.CheckWait(ctx, stop_ctx, count, err)
+			s.mu.Lock()
+			s.lastErr = err
+			s.mu.Unlock()
+
+			//主动请求的重启不是异常退出，立即重启，不受重启策略、延时和次数的限制，并重置重启计数
+			if s.restartAsked.Swap(false) && stop_ctx.Err() == nil {
+				s.log.Debug("请求重启")
+				count = 0
+				continue
+			}
+
+			rc := s.cfg.Restart
+			//稳定运行足够长时间，重置重启计数
+			if rc.ResetAfter > 0 && time.Since(startAt) >= rc.ResetAfter {
+				count = 1
+			}
+
+			if rc.Exceeded(count) && rc.ShouldRestart(stop_ctx, err) {
+				s.log.Warn("重启次数超过限制", "max", rc.Max, "err", err)
+				statusUp(statusFailed)
+				return
+			}
+
+			restart := rc.checkWait(ctx, stop_ctx, count, err, func(delay time.Duration) {
+				ev := Event{Type: EventRestart, Count: count, Delay: jsonx.Duration(delay)}
+				if err != nil {
+					ev.Err = err.Error()
+				}
+				s.emit(ev)
+			})
 			if !restart {
 				return
 			}
@@ -177,6 +291,8 @@ func Start(ctx context.Context, options ...Option) *Program {
 	//启动, 等待信号
 	go func(ctx context.Context) {
 		defer close(done)
+		defer s.events.close()
+		defer s.output.close()
 
 		defer s.log.Debug("🔚 结束")
 		s.log.Debug("初始化完成")
@@ -184,7 +300,7 @@ func Start(ctx context.Context, options ...Option) *Program {
 		for {
 			startSignal := make(chan struct{})
 			closeSignal := sync.OnceFunc(func() { close(startSignal) })
-			s.start = closeSignal
+			s.swap(&s.start, closeSignal)
 			setInitialized()
 
 			select {
@@ -204,21 +320,114 @@ func Start(ctx context.Context, options ...Option) *Program {
 }
 
 // 启动
-func (s *Program) Start() error { s.call(s.start, "启动"); return nil }
+func (s *Program) Start() error { s.call(&s.start, "启动"); return nil }
 
-// 重启
-func (s *Program) Restart() error { s.call(s.restart, "重启"); return nil }
+// 重启，立即重新启动正在运行的程序，不受重启策略、延时和次数(Max)的限制
+func (s *Program) Restart() error {
+	s.mu.Lock()
+	if s.restart != nil {
+		s.restartAsked.Store(true)
+	}
+	s.mu.Unlock()
+	s.call(&s.restart, "重启")
+	return nil
+}
 
 // 停止
-func (s *Program) Stop() error { s.call(s.stop, "停止"); return nil }
+func (s *Program) Stop() error { s.call(&s.stop, "停止"); return nil }
+
+// Pid 取得正在运行的进程号，未运行时返回0
+func (s *Program) Pid() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc == nil {
+		return 0
+	}
+	return s.proc.Pid
+}
+
+// Signal 向正在运行的进程(组)发送信号
+func (s *Program) Signal(sig os.Signal) error {
+	s.mu.Lock()
+	proc := s.proc
+	s.mu.Unlock()
+
+	if proc == nil {
+		return errx.Errorf("cmdx: not running")
+	}
+	s.log.Debug("发送信号", "signal", sig.String())
+	return cmdo.Signal(proc, sig)
+}
+
+// Reload 发送 SIGHUP 信号通知进程重新加载，不计入重启
+func (s *Program) Reload() error { return s.Signal(reloadSignal) }
 
 // 取得退出信号
 func (s *Program) Done() <-chan struct{} { return s.done }
 
+// 取得最后一次退出的错误，正常退出或尚未退出时为 nil
+func (s *Program) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// 取得最后一次进程退出的信息，尚未退出过时返回 nil
+func (s *Program) LastExit() *ExitInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastExit == nil {
+		return nil
+	}
+	exit := *s.lastExit
+	return &exit
+}
+
+// Subscribe 订阅程序事件，buffer 为通道缓冲大小(最小16)，接收不及时的事件会被丢弃
+//
+// 返回值: 事件通道及取消订阅的方法，程序结束(Done)后通道自动关闭
+func (s *Program) Subscribe(buffer int) (events <-chan Event, cancel func()) {
+	return s.events.subscribe(buffer)
+}
+
 // 取得状态
-func (s *Program) Status() string { return s.status }
+func (s *Program) Status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Tail 取得最近 n 行输出(包含 stdout 和 stderr)，n<=0 时返回保留的全部行，与日志输出到哪里无关
+func (s *Program) Tail(n int) []Line { return s.output.tail(n) }
+
+// SubscribeOutput 订阅程序输出，先推送最近的 backlog 行，再推送新的输出，其他用法同 Subscribe
+func (s *Program) SubscribeOutput(buffer, backlog int) (lines <-chan Line, cancel func()) {
+	return s.output.subscribe(buffer, backlog)
+}
+
+func (s *Program) emit(ev Event) {
+	ev.Time = time.Now()
+	s.events.publish(ev)
+}
+
+func (s *Program) setProc(proc *os.Process) {
+	s.mu.Lock()
+	s.proc = proc
+	s.mu.Unlock()
+}
+
+// swap 在锁内替换取消函数，返回原来的取消函数
+func (s *Program) swap(p *context.CancelFunc, cancel context.CancelFunc) (prev context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, *p = *p, cancel
+	return
+}
 
-func (s *Program) call(cancel context.CancelFunc, name string) {
+func (s *Program) call(p *context.CancelFunc, name string) {
+	s.mu.Lock()
+	cancel := *p
+	s.mu.Unlock()
 	if cancel != nil {
 		slog.Debug("请求命令: " + name)
 		cancel()
@@ -0,0 +1,117 @@
+package cmdx
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// 事件类型
+const (
+	EventStatus  = "status"  //状态变更
+	EventStarted = "started" //进程已启动
+	EventExited  = "exited"  //进程已退出
+	EventRestart = "restart" //已安排重启
+	EventHealth  = "health"  //健康状态变更
+)
+
+// Event 程序运行过程中产生的事件
+type Event struct {
+	Type   string         `json:"type"`
+	Time   time.Time      `json:"time"`
+	Pid    int            `json:"pid,omitempty"`    //started
+	Status string         `json:"status,omitempty"` //status
+	Exit   *ExitInfo      `json:"exit,omitempty"`   //exited
+	Count  int            `json:"count,omitempty"`  //restart: 第几次重启
+	Delay  jsonx.Duration `json:"delay,omitempty"`  //restart: 重启前的等待时长
+	Health string         `json:"health,omitempty"` //health: healthy, unhealthy
+	Err    string         `json:"err,omitempty"`    //restart, health: 触发的错误
+}
+
+// ExitInfo 进程退出信息
+type ExitInfo struct {
+	Pid      int            `json:"pid"`
+	Code     int            `json:"code"`             //退出码，被信号终止时为 -1
+	Signal   string         `json:"signal,omitempty"` //终止进程的信号
+	StartAt  time.Time      `json:"start_at"`
+	ExitAt   time.Time      `json:"exit_at"`
+	Duration jsonx.Duration `json:"duration"`
+	Err      string         `json:"err,omitempty"`
+}
+
+// newExitInfo 根据进程状态生成退出信息，state 可能为 nil (等待进程出错时)
+func newExitInfo(pid int, startAt time.Time, state *os.ProcessState, err error) *ExitInfo {
+	now := time.Now()
+	info := &ExitInfo{Pid: pid, Code: -1, StartAt: startAt, ExitAt: now, Duration: jsonx.Duration(now.Sub(startAt))}
+	if err != nil {
+		info.Err = err.Error()
+	}
+	if state != nil {
+		info.Code = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			info.Signal = ws.Signal().String()
+		}
+	}
+	return info
+}
+
+// hub 事件分发，订阅者接收不及时的数据会被丢弃，不会阻塞程序运行
+type hub[T any] struct {
+	subs   map[chan T]struct{}
+	closed bool
+	mu     sync.Mutex
+}
+
+func (h *hub[T]) publish(v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
+
+// subscribe 订阅，backlog 会在订阅时先放入通道
+func (h *hub[T]) subscribe(buffer int, backlog ...T) (<-chan T, func()) {
+	ch := make(chan T, max(buffer, 16)+len(backlog))
+	for _, v := range backlog {
+		ch <- v
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if h.subs == nil {
+		h.subs = map[chan T]struct{}{}
+	}
+	h.subs[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *hub[T]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		close(ch)
+	}
+	h.subs = nil
+}
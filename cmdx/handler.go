@@ -0,0 +1,150 @@
+package cmdx
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/webx"
+	"github.com/cnk3x/pkg/webx/respond"
+)
+
+// Programs 按名称查找程序，Supervisor 实现了此接口
+type Programs interface {
+	Names() []string
+	Program(name string) *Program
+}
+
+// ProgramMap 以名称为键的程序集合，用于在 Supervisor 之外使用 Handler
+type ProgramMap map[string]*Program
+
+func (m ProgramMap) Names() []string              { return slices.Sorted(maps.Keys(m)) }
+func (m ProgramMap) Program(name string) *Program { return m[name] }
+
+// ProgramInfo 程序状态信息
+type ProgramInfo struct {
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Pid      int       `json:"pid,omitempty"`
+	LastExit *ExitInfo `json:"last_exit,omitempty"`
+	Err      string    `json:"err,omitempty"`
+}
+
+// Info 取得程序状态信息
+func (s *Program) Info(name string) ProgramInfo {
+	info := ProgramInfo{Name: name, Status: s.Status(), Pid: s.Pid(), LastExit: s.LastExit()}
+	if err := s.Err(); err != nil {
+		info.Err = err.Error()
+	}
+	return info
+}
+
+// Handler 程序控制接口，可使用 webx.Strip 挂载到任意前缀下
+//
+//	GET  /                 列出所有程序及状态
+//	GET  /{name}           取得程序状态
+//	POST /{name}/{action}  控制程序, action: start, stop, restart, reload
+//	GET  /{name}/tail?n=   取得最近 n 行输出
+//	GET  /{name}/log?n=    以 SSE 推送输出行，先推送最近 n 行
+func Handler(programs Programs) http.Handler {
+	find := func(ctx context.Context) (string, *Program) {
+		name, _ := ctx.Value(pathNameKey).(string)
+		p, _ := ctx.Value(programKey).(*Program)
+		return name, p
+	}
+
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /{$}", webx.Handle(func(ctx context.Context, _ *struct{}) (list []ProgramInfo, err error) {
+		list = []ProgramInfo{}
+		for _, name := range programs.Names() {
+			if p := programs.Program(name); p != nil {
+				list = append(list, p.Info(name))
+			}
+		}
+		return
+	}))
+
+	mux.Handle("GET /{name}", withProgram(programs, webx.Handle(func(ctx context.Context, _ *struct{}) (info ProgramInfo, err error) {
+		name, p := find(ctx)
+		return p.Info(name), nil
+	})))
+
+	mux.Handle("POST /{name}/{action}", withProgram(programs, webx.Handle(func(ctx context.Context, _ *struct{}) (info ProgramInfo, err error) {
+		name, p := find(ctx)
+		switch action, _ := ctx.Value(pathActionKey).(string); action {
+		case "start":
+			err = p.Start()
+		case "stop":
+			err = p.Stop()
+		case "restart":
+			err = p.Restart()
+		case "reload":
+			err = p.Reload()
+		}
+
+		if err == nil {
+			info = p.Info(name)
+		}
+		return
+	})))
+
+	mux.Handle("GET /{name}/tail", withProgram(programs, webx.Handle(func(ctx context.Context, in *tailQuery) (lines []Line, err error) {
+		_, p := find(ctx)
+		return p.Tail(cmp.Or(in.N, 100)), nil
+	})))
+
+	mux.Handle("GET /{name}/log", withProgram(programs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, p := find(r.Context())
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		lines, cancel := p.SubscribeOutput(256, n)
+		defer cancel()
+		respond.ServerEvent(w, r, respond.ServerEventSource[Line]{Heartbeat: 30, Data: lines})
+	})))
+
+	return mux
+}
+
+type tailQuery struct {
+	N int `json:"n" form:"n"`
+}
+
+type pathKey struct{ name string }
+
+var (
+	pathNameKey   = &pathKey{"name"}
+	pathActionKey = &pathKey{"action"}
+	programKey    = &pathKey{"program"}
+
+	// actions 支持的控制操作
+	actions = []string{"start", "stop", "restart", "reload"}
+)
+
+// withProgram 将路径参数和对应的程序放入上下文，供 webx.Handle 的处理函数读取，程序不存在时返回 404，操作不支持时返回 400
+func withProgram(programs Programs, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, action := r.PathValue("name"), r.PathValue("action")
+
+		p := programs.Program(name)
+		if p == nil {
+			respond.Status(r, http.StatusNotFound)
+			respond.Respond(w, r, respond.E(errx.Errorf("cmdx: program %q not found", name), "NOT_FOUND"))
+			return
+		}
+
+		if action != "" && !slices.Contains(actions, action) {
+			respond.Status(r, http.StatusBadRequest)
+			respond.Respond(w, r, respond.E(errx.Errorf("cmdx: unknown action %q", action), "UNKNOWN_ACTION"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), pathNameKey, name)
+		ctx = context.WithValue(ctx, pathActionKey, action)
+		ctx = context.WithValue(ctx, programKey, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
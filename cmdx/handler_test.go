@@ -0,0 +1,131 @@
+package cmdx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	p := Start(ctx, Use(Config{Path: "sh", Args: []string{"-c", "echo hello; sleep 30"}}))
+	defer p.Stop()
+
+	srv := httptest.NewServer(Handler(ProgramMap{"app": p}))
+	defer srv.Close()
+
+	do := func(method, path string) (int, []byte) {
+		req, _ := http.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return resp.StatusCode, body.Bytes()
+	}
+
+	//等待程序进入指定状态，返回其信息
+	waitInfo := func(status string, ok func(ProgramInfo) bool) (info ProgramInfo) {
+		for {
+			code, body := do(http.MethodGet, "/app")
+			if code != http.StatusOK {
+				t.Fatalf("取得程序状态失败: %d %s", code, body)
+			}
+			info = ProgramInfo{} //pid 为空时不输出，需要重置
+			if err := json.Unmarshal(body, &info); err != nil {
+				t.Fatal(err)
+			}
+			if info.Status == status && (ok == nil || ok(info)) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("程序未进入 %s 状态: %+v", status, info)
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}
+
+	pid := waitInfo(statusRunning, func(info ProgramInfo) bool { return info.Pid != 0 }).Pid
+
+	code, body := do(http.MethodGet, "/")
+	var list []ProgramInfo
+	if err := json.Unmarshal(body, &list); err != nil || code != http.StatusOK || len(list) != 1 || list[0].Name != "app" || list[0].Pid != pid {
+		t.Fatalf("程序列表错误: %d %s %v", code, body, err)
+	}
+
+	for _, c := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound},
+		{http.MethodPost, "/missing/start", http.StatusNotFound},
+		{http.MethodGet, "/missing/tail", http.StatusNotFound},
+		{http.MethodGet, "/missing/log", http.StatusNotFound},
+		{http.MethodPost, "/app/bogus", http.StatusBadRequest},
+	} {
+		if code, body := do(c.method, c.path); code != c.code {
+			t.Errorf("%s %s: 期望 %d，实际 %d %s", c.method, c.path, c.code, code, body)
+		}
+	}
+
+	var lines []Line
+	for len(lines) == 0 {
+		code, body = do(http.MethodGet, "/app/tail?n=10")
+		if err := json.Unmarshal(body, &lines); err != nil || code != http.StatusOK {
+			t.Fatalf("取得输出失败: %d %s %v", code, body, err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("未读到输出")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	if lines[0].Text != "hello" {
+		t.Errorf("输出错误: %v", lineTexts(lines))
+	}
+
+	//SSE 推送最近的输出
+	logCtx, logCancel := context.WithCancel(ctx)
+	req, _ := http.NewRequestWithContext(logCtx, http.MethodGet, srv.URL+"/app/log?n=10", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("日志推送失败: %d %s", resp.StatusCode, ct)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && !strings.Contains(scanner.Text(), `"hello"`) {
+	}
+	if !strings.Contains(scanner.Text(), `"hello"`) {
+		t.Errorf("日志推送未包含最近的输出: %v", scanner.Err())
+	}
+	logCancel()
+	resp.Body.Close()
+
+	if code, body = do(http.MethodPost, "/app/restart"); code != http.StatusOK {
+		t.Fatalf("重启失败: %d %s", code, body)
+	}
+	waitInfo(statusRunning, func(info ProgramInfo) bool { return info.Pid != 0 && info.Pid != pid })
+
+	if code, body = do(http.MethodPost, "/app/stop"); code != http.StatusOK {
+		t.Fatalf("停止失败: %d %s", code, body)
+	}
+	waitInfo(statusStopped, func(info ProgramInfo) bool { return info.Pid == 0 })
+
+	if code, body = do(http.MethodPost, "/app/start"); code != http.StatusOK {
+		t.Fatalf("启动失败: %d %s", code, body)
+	}
+	waitInfo(statusRunning, func(info ProgramInfo) bool { return info.Pid != 0 })
+}
@@ -6,6 +6,15 @@ type Option func(*Program)
 
 func (x Option) apply(p *Program) { x(p) }
 
+// Use 设置程序的运行配置，已通过 ProcessInline 设置的行处理函数会被保留
+func Use(cfg Config) Option {
+	return func(p *Program) {
+		processInline := p.cfg.Log.processInline
+		p.cfg = cfg
+		p.cfg.Log.processInline = processInline
+	}
+}
+
 func Prepare(onPrepare func(p *Config) error) Option {
 	return func(p *Program) { p.onPrepare = onPrepare }
 }
@@ -0,0 +1,113 @@
+package cmdx
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// HealthConfig 健康检查配置, http, tcp, unix, exec 四选一，都为空时不检查
+//
+// 连续失败次数达到 Retries 后状态变为 unhealthy，并通过重启流程重启程序(是否重启取决于 RestartConfig.Type)
+type HealthConfig struct {
+	HTTP string        `json:"http,omitempty"` //GET 请求地址，返回 2xx/3xx 视为健康
+	TCP  string        `json:"tcp,omitempty"`  //TCP 地址，能够连接视为健康
+	Unix string        `json:"unix,omitempty"` //unix socket 路径，能够连接视为健康
+	Exec jsonx.Strings `json:"exec,omitempty"` //命令及参数，退出码为0视为健康
+
+	Interval    jsonx.Duration `json:"interval,omitempty"`     //检查间隔，默认10s
+	Timeout     jsonx.Duration `json:"timeout,omitempty"`      //单次检查超时，默认5s
+	Retries     int            `json:"retries,omitempty"`      //连续失败多少次视为不健康，默认3
+	StartPeriod jsonx.Duration `json:"start_period,omitempty"` //启动后等待多久开始检查，默认与检查间隔相同
+}
+
+// Enabled 是否配置了健康检查
+func (h HealthConfig) Enabled() bool {
+	return h.HTTP != "" || h.TCP != "" || h.Unix != "" || len(h.Exec) > 0
+}
+
+// Check 执行一次健康检查，健康返回 nil
+func (h HealthConfig) Check(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(h.Timeout.Value(), time.Second*5))
+	defer cancel()
+
+	switch {
+	case h.HTTP != "":
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, h.HTTP, nil); err != nil {
+			return errx.Errorf("health: %w", err)
+		}
+		var resp *http.Response
+		if resp, err = http.DefaultClient.Do(req); err != nil {
+			return errx.Errorf("health: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return errx.Errorf("health: http status %d", resp.StatusCode)
+		}
+	case h.TCP != "":
+		err = dialCheck(ctx, "tcp", h.TCP)
+	case h.Unix != "":
+		err = dialCheck(ctx, "unix", h.Unix)
+	case len(h.Exec) > 0:
+		if err = exec.CommandContext(ctx, h.Exec[0], h.Exec[1:]...).Run(); err != nil {
+			return errx.Errorf("health: %w", err)
+		}
+	}
+	return
+}
+
+// Watch 按间隔循环检查，健康状态变化时调用 onChange，连续失败达到重试次数后视为不健康，ctx 结束时退出
+func (h HealthConfig) Watch(ctx context.Context, log *slog.Logger, onChange func(healthy bool, err error)) {
+	if !h.Enabled() {
+		return
+	}
+
+	interval := max(cmp.Or(h.Interval.Value(), time.Second*10), time.Second)
+	retries := max(cmp.Or(h.Retries, 3), 1)
+
+	wait := cmp.Or(h.StartPeriod.Value(), interval)
+	for failures, healthy := 0, false; ; {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+			wait = interval
+		}
+
+		err := h.Check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			if failures = 0; !healthy {
+				healthy = true
+				onChange(true, nil)
+			}
+			continue
+		}
+
+		failures++
+		log.Debug("健康检查失败", "count", failures, "err", err)
+		if failures >= retries && (healthy || failures == retries) {
+			healthy = false
+			onChange(false, err)
+		}
+	}
+}
+
+func dialCheck(ctx context.Context, network, addr string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+	if err != nil {
+		return errx.Errorf("health: %w", err)
+	}
+	return conn.Close()
+}
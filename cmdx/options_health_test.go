@@ -0,0 +1,102 @@
+package cmdx
+
+import (
+	"cmp"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+func TestHealthCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	for _, c := range []struct {
+		name    string
+		health  HealthConfig
+		healthy bool
+	}{
+		{"http", HealthConfig{HTTP: srv.URL}, true},
+		{"http 503", HealthConfig{HTTP: srv.URL + "/bad"}, false},
+		{"tcp", HealthConfig{TCP: srv.Listener.Addr().String()}, true},
+		{"tcp closed", HealthConfig{TCP: addr}, false},
+		{"exec", HealthConfig{Exec: []string{"true"}}, true},
+		{"exec fail", HealthConfig{Exec: []string{"false"}}, false},
+		{"exec timeout", HealthConfig{Exec: []string{"sleep", "5"}, Timeout: jsonx.Duration(time.Millisecond * 100)}, false},
+	} {
+		if err := c.health.Check(t.Context()); (err == nil) != c.healthy {
+			t.Errorf("%s: 检查结果错误: %v", c.name, err)
+		}
+	}
+}
+
+func TestHealthRestart(t *testing.T) {
+	tests := []struct {
+		restart string
+		want    bool //是否重启
+	}{
+		{"always", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.restart, "none"), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			p := Start(ctx, Use(Config{
+				Path:    "sleep",
+				Args:    []string{"30"},
+				Restart: RestartConfig{Type: tt.restart, Delay: time.Second / 2},
+				Health:  HealthConfig{Exec: []string{"false"}, Retries: 1, Interval: jsonx.Duration(time.Millisecond * 50), StartPeriod: jsonx.Duration(time.Millisecond * 10)},
+			}))
+			defer p.Stop()
+
+			events, unsubscribe := p.Subscribe(64)
+			defer unsubscribe()
+
+			var unhealthy, restarted bool
+			for !restarted {
+				select {
+				case ev := <-events:
+					switch {
+					case ev.Type == EventHealth && ev.Health == "unhealthy":
+						unhealthy = true
+					case ev.Type == EventStarted && unhealthy:
+						restarted = true
+					}
+				case <-time.After(time.Second):
+					if tt.want {
+						t.Fatalf("健康检查失败后未重启: unhealthy=%v", unhealthy)
+					}
+					//不重启时只报告不健康，进程继续运行
+					if !unhealthy || p.Status() != statusUnhealthy || p.Pid() == 0 {
+						t.Fatalf("不健康时状态错误: unhealthy=%v status=%s pid=%d", unhealthy, p.Status(), p.Pid())
+					}
+					return
+				case <-ctx.Done():
+					t.Fatal(context.Cause(ctx))
+				}
+			}
+			if !tt.want {
+				t.Fatal("不应重启")
+			}
+		})
+	}
+}
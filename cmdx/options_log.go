@@ -14,13 +14,15 @@ type logConfig struct {
 	Out string `json:"out,omitempty"`
 	Err string `json:"err,omitempty"`
 
+	LogRotate //日志轮转，仅对文件日志生效
+
 	processInline func(s string, stdout bool)
 }
 
 type LogConfig logConfig
 
 func (p LogConfig) MarshalJSON() ([]byte, error) {
-	if p.Out == p.Err {
+	if p.Out == p.Err && p.LogRotate == (LogRotate{}) {
 		return json.Marshal(p.Out)
 	}
 	return json.Marshal(logConfig(p))
@@ -48,7 +50,7 @@ func (p *LogConfig) UnmarshalJSON(data []byte) (err error) {
 func (p *LogConfig) Open() (stdout *os.File, stderr *os.File, closeIt func(), err error) {
 	nOut, nErr := normalizeLog(p.Out, true), normalizeLog(p.Err, false)
 
-	if stdout, err = createLog(nOut, func(line string) {
+	if stdout, err = createLog(nOut, p.LogRotate, func(line string) {
 		if p.processInline != nil {
 			p.processInline(line, true)
 		} else {
@@ -59,7 +61,7 @@ func (p *LogConfig) Open() (stdout *os.File, stderr *os.File, closeIt func(), er
 	}
 
 	if nErr != nOut {
-		if stderr, err = createLog(nErr, func(line string) {
+		if stderr, err = createLog(nErr, p.LogRotate, func(line string) {
 			if p.processInline != nil {
 				p.processInline(line, false)
 			} else {
@@ -87,7 +89,7 @@ func (p *LogConfig) Open() (stdout *os.File, stderr *os.File, closeIt func(), er
 	return
 }
 
-func createLog(log string, processInline func(line string)) (*os.File, error) {
+func createLog(log string, rotate LogRotate, processInline func(line string)) (*os.File, error) {
 	switch log {
 	case "nul":
 		return nil, nil
@@ -112,6 +114,9 @@ func createLog(log string, processInline func(line string)) (*os.File, error) {
 		if err := os.MkdirAll(filepath.Dir(log), 0755); err != nil {
 			return nil, fmt.Errorf("create log dir error: %w", err)
 		}
+		if rotate.Enabled() {
+			return openRotate(log, rotate)
+		}
 		return os.OpenFile(log, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	}
 }
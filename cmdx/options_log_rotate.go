@@ -0,0 +1,231 @@
+package cmdx
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// 轮转备份文件名中的时间格式
+const rotateTimeFormat = "2006-01-02T15-04-05.000"
+
+// LogRotate 文件日志的轮转配置，都为零值时不轮转
+type LogRotate struct {
+	MaxSize    int            `json:"max_size,omitempty"`    //单个文件最大大小(MB)，超过后轮转
+	MaxAge     jsonx.Duration `json:"max_age,omitempty"`     //备份保留时长，超过后删除
+	MaxBackups int            `json:"max_backups,omitempty"` //最多保留多少个备份
+	Compress   bool           `json:"compress,omitempty"`    //是否使用 gzip 压缩备份
+	Daily      bool           `json:"daily,omitempty"`       //是否每天轮转
+}
+
+// Enabled 是否需要轮转
+func (r LogRotate) Enabled() bool { return r.MaxSize > 0 || r.Daily }
+
+// openRotate 打开一个轮转日志，返回管道的写入端，关闭写入端后日志文件随之关闭
+func openRotate(log string, rotate LogRotate) (*os.File, error) {
+	rw := &rotateWriter{path: log, rotate: rotate}
+	if err := rw.open(); err != nil {
+		return nil, fmt.Errorf("open log error: %w", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		rw.Close()
+		return nil, fmt.Errorf("create rotate log error: %w", err)
+	}
+
+	go func() {
+		defer r.Close()
+		defer rw.Close()
+		if _, err := io.Copy(rw, r); err != nil {
+			slog.Debug("rotate log copy", "path", log, "err", err)
+		}
+	}()
+
+	return w, nil
+}
+
+// rotateWriter 按大小或日期轮转的文件写入器
+type rotateWriter struct {
+	path   string
+	rotate LogRotate
+
+	file *os.File
+	size int64
+	day  string
+
+	mu   sync.Mutex
+	mill sync.Mutex
+}
+
+func (w *rotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shouldRotate(int64(len(p))) {
+		if err = w.roll(); err != nil {
+			return
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return
+}
+
+func (w *rotateWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	return
+}
+
+func (w *rotateWriter) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.rotate.MaxSize > 0 && w.size+n > int64(w.rotate.MaxSize)<<20 {
+		return true
+	}
+	return w.rotate.Daily && time.Now().Format(time.DateOnly) != w.day
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w.file, w.size, w.day = f, 0, time.Now().Format(time.DateOnly)
+	if stat, e := f.Stat(); e == nil {
+		w.size = stat.Size()
+		if w.size > 0 {
+			w.day = stat.ModTime().Format(time.DateOnly)
+		}
+	}
+	return nil
+}
+
+// roll 关闭当前文件，重命名为备份，重新打开，在后台压缩并清理过期备份
+func (w *rotateWriter) roll() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	ext := filepath.Ext(w.path)
+	backup := strings.TrimSuffix(w.path, ext) + "-" + time.Now().Format(rotateTimeFormat) + ext
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	go w.millRun(backup)
+	return nil
+}
+
+func (w *rotateWriter) millRun(backup string) {
+	w.mill.Lock()
+	defer w.mill.Unlock()
+
+	if w.rotate.Compress {
+		if err := gzipFile(backup); err != nil {
+			slog.Warn("compress log fail", "path", backup, "err", err)
+		}
+	}
+
+	if w.rotate.MaxBackups <= 0 && w.rotate.MaxAge <= 0 {
+		return
+	}
+
+	backups := w.backups()
+	for i, b := range backups {
+		expired := w.rotate.MaxAge > 0 && time.Since(b.ModTime()) > w.rotate.MaxAge.Value()
+		if expired || (w.rotate.MaxBackups > 0 && i >= w.rotate.MaxBackups) {
+			if err := os.Remove(b.path); err != nil {
+				slog.Warn("remove log backup fail", "path", b.path, "err", err)
+			}
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	os.FileInfo
+}
+
+// backups 列出所有备份，按时间从新到旧排列
+func (w *rotateWriter) backups() (backups []backupFile) {
+	dir, name := filepath.Split(w.path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		n := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(n, prefix) {
+			continue
+		}
+
+		ts := strings.TrimSuffix(strings.TrimSuffix(n[len(prefix):], ".gz"), ext)
+		if _, e := time.Parse(rotateTimeFormat, ts); e != nil {
+			continue
+		}
+
+		if info, e := entry.Info(); e == nil {
+			backups = append(backups, backupFile{filepath.Join(dir, n), info})
+		}
+	}
+
+	slices.SortFunc(backups, func(a, b backupFile) int { return strings.Compare(b.Name(), a.Name()) })
+	return
+}
+
+func gzipFile(src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	zw := gzip.NewWriter(out)
+	if _, err = io.Copy(zw, in); err == nil {
+		err = zw.Close()
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(src + ".gz")
+		return
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,76 @@
+package cmdx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateSize(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "app.log")
+	w := &rotateWriter{path: log, rotate: LogRotate{MaxSize: 1, MaxBackups: 2, Compress: true}}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	chunk := bytes.Repeat([]byte("x"), 700<<10)
+	for range 5 {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond) //备份文件名精确到毫秒
+	}
+
+	//压缩和清理在后台进行
+	var backups []backupFile
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		w.mill.Lock()
+		backups = w.backups()
+		w.mill.Unlock()
+		if len(backups) == 2 && strings.HasSuffix(backups[0].path, ".gz") && strings.HasSuffix(backups[1].path, ".gz") {
+			break
+		}
+	}
+	if len(backups) != 2 {
+		t.Fatalf("备份数量错误: %d", len(backups))
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b.path, ".log.gz") {
+			t.Errorf("备份未压缩: %s", b.path)
+		}
+	}
+
+	if stat, err := os.Stat(log); err != nil || stat.Size() != int64(len(chunk)) {
+		t.Fatalf("当前日志大小错误: %v %v", stat.Size(), err)
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "app.log")
+	w := &rotateWriter{path: log, rotate: LogRotate{Daily: true}}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("yesterday\n")); err != nil {
+		t.Fatal(err)
+	}
+	w.day = "2000-01-01"
+	if _, err := w.Write([]byte("today\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(log); string(data) != "today\n" {
+		t.Fatalf("当前日志内容错误: %q", data)
+	}
+	if backups := w.backups(); len(backups) != 1 {
+		t.Fatalf("备份数量错误: %d", len(backups))
+	} else if data, _ := os.ReadFile(backups[0].path); string(data) != "yesterday\n" {
+		t.Fatalf("备份内容错误: %q", data)
+	}
+}
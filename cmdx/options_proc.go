@@ -0,0 +1,46 @@
+package cmdx
+
+import (
+	"log/slog"
+	"os/exec"
+	"strconv"
+
+	"github.com/cnk3x/pkg/errx"
+)
+
+// CgroupConfig cgroup v2 配置，Path 为空时不启用，主机不支持或没有权限时忽略并输出警告(仅 linux)
+type CgroupConfig struct {
+	Path   string  `json:"path,omitempty"`   //相对于 /sys/fs/cgroup 的路径，如 cmdx/app
+	Memory int     `json:"memory,omitempty"` //内存上限(MB)
+	CPU    float64 `json:"cpu,omitempty"`    //CPU上限(核数)，如 0.5
+}
+
+// procPrepare 启动前应用用户、用户组和 cgroup，返回启动命令的方法以及退出后的清理方法
+//
+// 用户、用户组、umask 配置错误时返回错误，cgroup 创建失败时仅输出警告，优先级和资源限制见 startLimited
+func (x Config) procPrepare(c *exec.Cmd, log *slog.Logger) (start func() error, cleanup func(), err error) {
+	if err = setCredential(c, x.User, x.Group); err != nil {
+		return
+	}
+
+	cleanup = func() {}
+	if x.Cgroup.Path != "" {
+		if cleanup, err = setCgroup(c, x.Cgroup); err != nil {
+			log.Warn("cgroup 不可用，已忽略", "path", x.Cgroup.Path, "err", err)
+			cleanup, err = func() {}, nil
+		}
+	}
+
+	umask := -1
+	if x.Umask != "" {
+		mask, e := strconv.ParseUint(x.Umask, 8, 32)
+		if e != nil || mask > 0777 {
+			err = errx.Errorf("cmdx: invalid umask %q", x.Umask)
+			return
+		}
+		umask = int(mask)
+	}
+
+	start = func() error { return startLimited(c, x.Nice, umask, x.Rlimits, log) }
+	return
+}
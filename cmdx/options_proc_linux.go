@@ -0,0 +1,199 @@
+package cmdx
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/cnk3x/pkg/errx"
+	"golang.org/x/sys/unix"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// rlimitResources 支持的资源限制名称，memory 同 as
+var rlimitResources = map[string]int{
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"as":      unix.RLIMIT_AS,
+	"memory":  unix.RLIMIT_AS,
+	"cpu":     unix.RLIMIT_CPU,
+	"core":    unix.RLIMIT_CORE,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"stack":   unix.RLIMIT_STACK,
+	"data":    unix.RLIMIT_DATA,
+	"memlock": unix.RLIMIT_MEMLOCK,
+}
+
+// procMu 串行执行需要临时提高当前进程资源限制的启动
+var procMu sync.Mutex
+
+// startLimited 启动命令，优先级、umask 和资源限制在 exec 之前生效，umask<0 时不设置
+//
+//   - 优先级: 在独占的系统线程上修改线程优先级后启动，子进程继承该线程的优先级，线程随 goroutine 结束退出，不影响当前进程
+//   - umask: 同一个线程先取消与其他线程共享的文件系统属性(unshare CLONE_FS)，再修改 umask，同样不影响当前进程
+//   - 资源限制: 使用 prlimit 命令包装程序，由 prlimit 设置限制后再执行程序，软限制和硬限制相同
+//   - 配置值超过当前进程的硬限制时，启动期间临时提高当前进程的硬限制(需要权限)，软限制不变，以便切换用户后的子进程可以设置
+//   - 系统中没有 prlimit 命令时，在启动后通过 prlimit 系统调用设置，并输出警告
+func startLimited(c *exec.Cmd, nice, umask int, rlimits map[string]uint64, log *slog.Logger) (err error) {
+	if c.Err != nil || (nice == 0 && umask < 0 && len(rlimits) == 0) {
+		return c.Start()
+	}
+
+	after := false //是否在启动后设置资源限制
+	if len(rlimits) > 0 {
+		if prlimit, e := exec.LookPath("prlimit"); e != nil {
+			log.Warn("未找到 prlimit 命令，资源限制在启动后设置", "err", e)
+			after = true
+		} else {
+			procMu.Lock()
+			defer procMu.Unlock()
+
+			valid, restore := raiseRlimits(rlimits, log)
+			defer restore()
+			wrapPrlimit(c, prlimit, valid)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		//不解除锁定，goroutine 结束后线程随之退出，修改过的优先级不会留给其他 goroutine
+		runtime.LockOSThread()
+		if nice != 0 {
+			if e := unix.Setpriority(unix.PRIO_PROCESS, unix.Gettid(), nice); e != nil {
+				log.Warn("设置优先级失败", "nice", nice, "err", e)
+			}
+		}
+		if umask >= 0 {
+			if e := unix.Unshare(unix.CLONE_FS); e != nil {
+				log.Warn("设置 umask 失败", "umask", fmt.Sprintf("%04o", umask), "err", e)
+			} else {
+				unix.Umask(umask)
+			}
+		}
+		done <- c.Start()
+	}()
+	if err = <-done; err != nil || !after {
+		return
+	}
+
+	if e := setRlimits(c.Process.Pid, rlimits); e != nil {
+		log.Warn("设置资源限制失败", "rlimits", rlimits, "err", e)
+	}
+	return
+}
+
+// raiseRlimits 配置值超过当前进程的硬限制时，临时提高当前进程的硬限制
+//
+// 返回可以设置的资源限制，名称未知或无法提高的输出警告后排除，以及恢复当前进程限制的方法
+func raiseRlimits(rlimits map[string]uint64, log *slog.Logger) (valid map[string]uint64, restore func()) {
+	valid = make(map[string]uint64, len(rlimits))
+	var restores []func()
+	for name, value := range rlimits {
+		resource, ok := rlimitResources[name]
+		if !ok {
+			log.Warn("未知的资源限制，已忽略", "name", name)
+			continue
+		}
+
+		var old syscall.Rlimit
+		if err := syscall.Getrlimit(resource, &old); err != nil {
+			log.Warn("读取资源限制失败，已忽略", "name", name, "err", err)
+			continue
+		}
+
+		//使用 syscall.Setrlimit 而不是 prlimit 系统调用，go 不再在子进程中把 nofile 恢复为启动时的限制
+		if value > old.Max {
+			if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: old.Cur, Max: value}); err != nil {
+				log.Warn("设置资源限制失败，已忽略", "name", name, "value", value, "err", err)
+				continue
+			}
+			restores = append(restores, func() { _ = syscall.Setrlimit(resource, &old) })
+		}
+		valid[name] = value
+	}
+
+	return valid, func() {
+		for _, r := range restores {
+			r()
+		}
+	}
+}
+
+// wrapPrlimit 使用 prlimit 命令包装程序，rlimits 为空时不包装
+func wrapPrlimit(c *exec.Cmd, prlimit string, rlimits map[string]uint64) {
+	if len(rlimits) == 0 {
+		return
+	}
+
+	args := []string{"prlimit"}
+	for _, name := range slices.Sorted(maps.Keys(rlimits)) {
+		v := strconv.FormatUint(rlimits[name], 10)
+		if rlimits[name] == ^uint64(0) {
+			v = "unlimited"
+		}
+		if name == "memory" {
+			name = "as"
+		}
+		args = append(args, "--"+name+"="+v+":"+v)
+	}
+	c.Args = append(append(args, "--", c.Path), c.Args[1:]...)
+	c.Path = prlimit
+}
+
+// setRlimits 通过 prlimit 设置子进程的资源限制，软限制和硬限制相同
+func setRlimits(pid int, rlimits map[string]uint64) (err error) {
+	for name, value := range rlimits {
+		resource, ok := rlimitResources[name]
+		if !ok {
+			err = errx.Join(err, errx.Errorf("cmdx: unknown rlimit %q", name))
+			continue
+		}
+		if e := unix.Prlimit(pid, resource, &unix.Rlimit{Cur: value, Max: value}, nil); e != nil {
+			err = errx.Join(err, errx.Errorf("cmdx: prlimit %s: %w", name, e))
+		}
+	}
+	return
+}
+
+// setCgroup 创建 cgroup v2 子目录并写入限制，子进程启动时直接放入该 cgroup
+func setCgroup(c *exec.Cmd, cfg CgroupConfig) (cleanup func(), err error) {
+	if _, err = os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, errx.Errorf("cmdx: cgroup v2 not mounted: %w", err)
+	}
+
+	dir := filepath.Join(cgroupRoot, filepath.Clean("/"+cfg.Path))
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	if cfg.Memory > 0 {
+		if err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.Itoa(cfg.Memory<<20)), 0644); err != nil {
+			return
+		}
+	}
+
+	if cfg.CPU > 0 {
+		const period = 100000
+		if err = os.WriteFile(filepath.Join(dir, "cpu.max"), fmt.Appendf(nil, "%d %d", int(cfg.CPU*period), period), 0644); err != nil {
+			return
+		}
+	}
+
+	fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+
+	c.SysProcAttr.UseCgroupFD = true
+	c.SysProcAttr.CgroupFD = fd
+	return func() { unix.Close(fd); os.Remove(dir) }, nil
+}
@@ -0,0 +1,86 @@
+package cmdx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 优先级和资源限制在 exec 之前生效，程序启动时即可读到
+func TestProcLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	p := Start(ctx, Use(Config{
+		Path:    "sh",
+		Args:    []string{"-c", "ulimit -Sn; ulimit -Hn; nice"},
+		Nice:    5,
+		Rlimits: map[string]uint64{"nofile": 1000},
+	}))
+	defer p.Stop()
+
+	var lines []string
+	for len(lines) < 3 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("未读到输出: %v", lines)
+		case <-time.After(time.Millisecond * 10):
+		}
+
+		lines = lines[:0]
+		for _, l := range p.Tail(0) {
+			lines = append(lines, l.Text)
+		}
+	}
+
+	if want := []string{"1000", "1000", "5"}; !slices.Equal(lines, want) {
+		t.Fatalf("优先级或资源限制未生效: got %v, want %v", lines, want)
+	}
+}
+
+// umask 只对子进程生效，子进程创建的文件权限按 umask 计算
+func TestProcUmask(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	parent := syscall.Umask(0022)
+	defer syscall.Umask(parent)
+
+	p := Start(ctx, Use(Config{Path: "sh", Args: []string{"-c", "touch f && mkdir d"}, Dir: dir, Umask: "077"}))
+	defer p.Stop()
+
+	for _, c := range []struct {
+		name string
+		mode os.FileMode
+	}{{"f", 0600}, {"d", 0700}} {
+		var stat os.FileInfo
+		for {
+			var err error
+			if stat, err = os.Stat(filepath.Join(dir, c.name)); err == nil {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("子进程未创建 %s: status=%s err=%v", c.name, p.Status(), p.Err())
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+		if perm := stat.Mode().Perm(); perm != c.mode {
+			t.Errorf("%s: 权限 %o，期望 %o", c.name, perm, c.mode)
+		}
+	}
+
+	if mask := syscall.Umask(0022); mask != 0022 {
+		t.Fatalf("当前进程的 umask 被修改: %o", mask)
+	}
+
+	if _, _, err := (Config{Umask: "9"}).procPrepare(exec.Command("true"), p.log); err == nil {
+		t.Fatal("无效的 umask 未报错")
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !linux
+
+package cmdx
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sync"
+
+	"github.com/cnk3x/pkg/errx"
+)
+
+// umaskMu 串行执行需要临时修改当前进程 umask 的启动
+var umaskMu sync.Mutex
+
+// startLimited 启动命令，非 linux 系统无法只对子进程设置，umask<0 时不设置
+//
+//   - umask: 启动期间临时修改当前进程的 umask，启动后恢复
+//   - 优先级: 启动后设置
+//   - 资源限制: 不支持
+func startLimited(c *exec.Cmd, nice, umask int, rlimits map[string]uint64, log *slog.Logger) (err error) {
+	if umask >= 0 {
+		umaskMu.Lock()
+		restore, e := setUmask(umask)
+		if e != nil {
+			log.Warn("设置 umask 失败", "umask", fmt.Sprintf("%04o", umask), "err", e)
+		} else {
+			defer restore()
+		}
+		defer umaskMu.Unlock()
+	}
+
+	if err = c.Start(); err != nil {
+		return
+	}
+	if nice != 0 {
+		if e := setNice(c.Process.Pid, nice); e != nil {
+			log.Warn("设置优先级失败", "nice", nice, "err", e)
+		}
+	}
+	if len(rlimits) > 0 {
+		log.Warn("资源限制仅支持 linux，已忽略", "rlimits", rlimits)
+	}
+	return
+}
+
+func setCgroup(c *exec.Cmd, cfg CgroupConfig) (func(), error) {
+	return nil, errx.Errorf("cmdx: cgroup is only supported on linux")
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package cmdx
+
+import (
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/cnk3x/pkg/errx"
+)
+
+// setCredential 设置子进程的用户和用户组，支持名称或数字id，仅设置用户时使用用户的主组
+func setCredential(c *exec.Cmd, username, groupname string) (err error) {
+	if username == "" && groupname == "" {
+		return
+	}
+
+	cred := &syscall.Credential{Uid: uint32(syscall.Getuid()), Gid: uint32(syscall.Getgid())}
+
+	if username != "" {
+		u, e := user.Lookup(username)
+		if e != nil {
+			if u, e = user.LookupId(username); e != nil {
+				return errx.Errorf("cmdx: lookup user %q: %w", username, e)
+			}
+		}
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+	}
+
+	if groupname != "" {
+		g, e := user.LookupGroup(groupname)
+		if e != nil {
+			if g, e = user.LookupGroupId(groupname); e != nil {
+				return errx.Errorf("cmdx: lookup group %q: %w", groupname, e)
+			}
+		}
+		gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+		cred.Gid = uint32(gid)
+	}
+
+	cred.NoSetGroups = true
+	c.SysProcAttr.Credential = cred
+	return
+}
+
+// setNice 设置进程优先级
+func setNice(pid int, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+// setUmask 修改当前进程的 umask，返回恢复的方法
+func setUmask(mask int) (restore func(), err error) {
+	old := syscall.Umask(mask)
+	return func() { syscall.Umask(old) }, nil
+}
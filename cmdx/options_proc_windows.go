@@ -0,0 +1,22 @@
+package cmdx
+
+import (
+	"os/exec"
+
+	"github.com/cnk3x/pkg/errx"
+)
+
+func setCredential(c *exec.Cmd, username, groupname string) error {
+	if username != "" || groupname != "" {
+		return errx.Errorf("cmdx: user and group are not supported on windows")
+	}
+	return nil
+}
+
+func setNice(pid int, nice int) error {
+	return errx.Errorf("cmdx: nice is not supported on windows")
+}
+
+func setUmask(mask int) (func(), error) {
+	return nil, errx.Errorf("cmdx: umask is not supported on windows")
+}
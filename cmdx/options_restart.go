@@ -4,19 +4,27 @@ import (
 	"cmp"
 	"context"
 	"encoding/json"
+	"math"
+	"math/rand/v2"
 	"time"
 )
 
 type restartConfig struct {
 	Type  string        `json:"type,omitempty"` //none, alway, unless-stopped, on-failure
 	Delay time.Duration `json:"delay,omitempty"`
-	Max   int           `json:"max,omitempty"`
+	Max   int           `json:"max,omitempty"` //最多连续重启次数，超过后状态为 failed，0 不限制
+
+	Multiplier float64       `json:"multiplier,omitempty"`  //退避倍数，每次重启延时乘以该值，<=1 时固定延时
+	MaxDelay   time.Duration `json:"max_delay,omitempty"`   //退避延时上限
+	Jitter     float64       `json:"jitter,omitempty"`      //随机抖动比例(0~1)，延时在 ±delay*jitter 范围内随机浮动
+	ResetAfter time.Duration `json:"reset_after,omitempty"` //程序稳定运行超过该时长后重置重启计数，0 不重置
 }
 
 type RestartConfig restartConfig
 
 func (p RestartConfig) MarshalJSON() ([]byte, error) {
-	if p.Type == "" || p.Type == "none" || ((p.Delay == 0 || p.Delay == time.Second*5) && (p.Max == 0 || p.Max == 10)) {
+	simple := p.Multiplier == 0 && p.MaxDelay == 0 && p.Jitter == 0 && p.ResetAfter == 0
+	if p.Type == "" || p.Type == "none" || ((p.Delay == 0 || p.Delay == time.Second*5) && p.Max == 0 && simple) {
 		return json.Marshal(p.Type)
 	}
 	return json.Marshal(restartConfig(p))
@@ -38,32 +46,70 @@ func (p *RestartConfig) UnmarshalJSON(data []byte) (err error) {
 	return
 }
 
+// CheckWait 判断是否需要重启，需要重启时等待退避延时后返回 true
+//
+// 参数:
+//   - ctx: 程序生命周期上下文，结束后不再重启
+//   - stop_ctx: 本次运行的停止上下文
+//   - count: 连续重启次数，从1开始
+//   - err: 本次运行的退出错误
 func (p RestartConfig) CheckWait(ctx context.Context, stop_ctx context.Context, count int, err error) (restart bool) {
-	restart = func() bool {
-		switch p.Type {
-		case "always":
-			return true
-		case "unless-stopped":
-			return stop_ctx.Err() == nil
-		case "on-failure":
-			return stop_ctx.Err() == nil && err != nil
-		default:
-			return false
-		}
-	}()
-
-	if !restart {
+	return p.checkWait(ctx, stop_ctx, count, err, nil)
+}
+
+// checkWait 同 CheckWait，确定需要重启后，等待之前调用 onSchedule 通知等待时长
+func (p RestartConfig) checkWait(ctx context.Context, stop_ctx context.Context, count int, err error, onSchedule func(delay time.Duration)) (restart bool) {
+	if restart = p.ShouldRestart(stop_ctx, err) && !p.Exceeded(count); !restart {
 		return
 	}
 
-	delay := max(cmp.Or(p.Delay, time.Second*5), time.Second/2)
+	delay := p.Backoff(count)
+	if onSchedule != nil && ctx.Err() == nil {
+		onSchedule(delay)
+	}
 
 	select {
 	case <-ctx.Done():
 		return false //退出了
 	case <-stop_ctx.Done():
 		return true
-	case <-time.After(delay): //最低1s, 默认5s
+	case <-time.After(delay):
 		return true
 	}
 }
+
+// ShouldRestart 根据重启策略判断本次退出是否需要重启，不考虑重启次数
+func (p RestartConfig) ShouldRestart(stop_ctx context.Context, err error) bool {
+	switch p.Type {
+	case "always":
+		return true
+	case "unless-stopped":
+		return stop_ctx.Err() == nil
+	case "on-failure":
+		return stop_ctx.Err() == nil && err != nil
+	default:
+		return false
+	}
+}
+
+// Exceeded 连续重启次数是否已经超过 Max
+func (p RestartConfig) Exceeded(count int) bool { return p.Max > 0 && count > p.Max }
+
+// Backoff 计算第 count 次重启前的等待时长，默认5s，最低0.5s
+func (p RestartConfig) Backoff(count int) time.Duration {
+	delay := max(cmp.Or(p.Delay, time.Second*5), time.Second/2)
+
+	if p.Multiplier > 1 && count > 1 {
+		delay = time.Duration(min(float64(delay)*math.Pow(p.Multiplier, float64(count-1)), math.MaxInt64))
+	}
+
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+
+	if jitter := min(p.Jitter, 1); jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay))
+	}
+
+	return max(delay, time.Second/2)
+}
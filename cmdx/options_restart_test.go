@@ -0,0 +1,72 @@
+package cmdx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRestartConfigJSON(t *testing.T) {
+	cases := []struct {
+		rc   RestartConfig
+		want string
+	}{
+		{RestartConfig{Type: "always"}, `"always"`},
+		{RestartConfig{Type: "always", Delay: time.Second * 5}, `"always"`},
+		{RestartConfig{Type: "always", Max: 10}, `{"type":"always","max":10}`},
+		{RestartConfig{Type: "on-failure", Max: 3}, `{"type":"on-failure","max":3}`},
+	}
+
+	for _, c := range cases {
+		data, err := json.Marshal(c.rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.want {
+			t.Errorf("%+v: 期望 %s，实际 %s", c.rc, c.want, data)
+		}
+
+		var rc RestartConfig
+		if err = json.Unmarshal(data, &rc); err != nil {
+			t.Fatal(err)
+		}
+		if rc.Type != c.rc.Type || rc.Max != c.rc.Max {
+			t.Errorf("%s: 解析结果不一致 %+v", data, rc)
+		}
+	}
+}
+
+func TestRestartNotCounted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	p := Start(ctx, Use(Config{
+		Path:    "sleep",
+		Args:    []string{"30"},
+		Restart: RestartConfig{Type: "always", Delay: time.Second / 2, Max: 2},
+	}))
+	defer p.Stop()
+
+	//等待启动出新的进程，返回其进程号
+	waitPid := func(old int) int {
+		for {
+			if pid := p.Pid(); pid != 0 && pid != old && p.Status() == statusRunning {
+				return pid
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("程序未启动: status=%s", p.Status())
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}
+
+	pid := waitPid(0)
+	for range 3 {
+		if err := p.Restart(); err != nil {
+			t.Fatal(err)
+		}
+		pid = waitPid(pid)
+	}
+}
@@ -0,0 +1,74 @@
+package cmdx
+
+import (
+	"cmp"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/cnk3x/pkg/cmdo"
+	"github.com/cnk3x/pkg/errx"
+)
+
+// stopStep 停止序列中的一步，发送信号或者等待
+type stopStep struct {
+	sig  os.Signal
+	wait time.Duration
+}
+
+// stopSteps 解析停止序列
+//
+// StopSequence 由信号名称和等待时长组成，例如 ["TERM", "10s", "KILL"] 表示先发送 SIGTERM，10s 后仍未退出则发送 SIGKILL，
+// 未配置时为 [StopSignal(默认TERM), WaitDelay(最低5s), KILL]
+func (x Config) stopSteps() (steps []stopStep, err error) {
+	sequence := []string(x.StopSequence)
+	if len(sequence) == 0 {
+		sequence = []string{cmp.Or(x.StopSignal, "TERM"), max(x.WaitDelay.Value(), time.Second*5).String(), "KILL"}
+	}
+
+	for _, item := range sequence {
+		if d, e := time.ParseDuration(item); e == nil {
+			steps = append(steps, stopStep{wait: d})
+			continue
+		}
+
+		sig, e := cmdo.ParseSignal(item)
+		if e != nil {
+			return nil, errx.Errorf("cmdx: stop sequence: %w", e)
+		}
+		steps = append(steps, stopStep{sig: sig})
+	}
+	return
+}
+
+// stopWait 停止序列的总等待时长
+func stopWait(steps []stopStep) (total time.Duration) {
+	for _, step := range steps {
+		total += step.wait
+	}
+	return
+}
+
+// runStopSteps 依次执行停止序列，进程退出(exited 关闭)后立即结束
+func runStopSteps(log *slog.Logger, proc *os.Process, steps []stopStep, exited <-chan struct{}) {
+	for _, step := range steps {
+		if step.sig != nil {
+			log.Debug("发送停止信号", "signal", step.sig.String())
+			if err := cmdo.Signal(proc, step.sig); err != nil {
+				log.Debug("发送停止信号失败", "signal", step.sig.String(), "err", err)
+			}
+		}
+
+		if step.wait > 0 {
+			select {
+			case <-exited:
+				return
+			case <-time.After(step.wait):
+			}
+		}
+	}
+}
+
+// reloadSignal 重载信号
+var reloadSignal os.Signal = syscall.SIGHUP
@@ -0,0 +1,54 @@
+package cmdx
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+func TestStopSteps(t *testing.T) {
+	for _, c := range []struct {
+		cfg  Config
+		want []stopStep
+	}{
+		{Config{}, []stopStep{{sig: syscall.SIGTERM}, {wait: time.Second * 5}, {sig: syscall.SIGKILL}}},
+		{Config{StopSignal: "INT", WaitDelay: jsonx.Duration(time.Second * 10)}, []stopStep{{sig: syscall.SIGINT}, {wait: time.Second * 10}, {sig: syscall.SIGKILL}}},
+		{Config{StopSequence: jsonx.Strings{"SIGQUIT", "1s", "term", "500ms", "9"}}, []stopStep{{sig: syscall.SIGQUIT}, {wait: time.Second}, {sig: syscall.SIGTERM}, {wait: time.Millisecond * 500}, {sig: syscall.Signal(9)}}},
+	} {
+		steps, err := c.cfg.stopSteps()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(steps, c.want) {
+			t.Errorf("%+v: 期望 %v，实际 %v", c.cfg, c.want, steps)
+		}
+	}
+
+	if _, err := (Config{StopSequence: jsonx.Strings{"TERM", "FOO"}}).stopSteps(); err == nil {
+		t.Error("无效的信号名称未返回错误")
+	}
+}
+
+// 停止序列无效时加载配置即返回错误，不会等到运行时反复重启
+func TestCheckConfigs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "programs.json")
+	if err := os.WriteFile(file, []byte(`{"a":{"path":"true"},"b":{"path":"true","stop_sequence":["TERM","FOO"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigs(file); err == nil {
+		t.Error("LoadConfigs: 无效的停止序列未返回错误")
+	}
+
+	configs := map[string]Config{"b": {Path: "true", StopSequence: jsonx.Strings{"FOO"}}}
+	if err := NewSupervisor(nil).Reload(configs); err == nil {
+		t.Error("Reload: 无效的停止序列未返回错误")
+	}
+	if err := NewSupervisor(configs).Start(t.Context()); err == nil {
+		t.Error("Start: 无效的停止序列未返回错误")
+	}
+}
@@ -0,0 +1,109 @@
+//go:build !windows
+
+package cmdx
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cnk3x/pkg/cmdo"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// 忽略 TERM 的进程在等待时长过后被 KILL
+func TestStopEscalation(t *testing.T) {
+	c := cmdo.Apply(exec.CommandContext(t.Context(), "sh", "-c", `trap "" TERM; echo ready; sleep 30`), cmdo.PKill)
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Process.Kill()
+
+	//等待 trap 生效
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "ready\n" {
+		t.Fatalf("未读到输出: %q", line)
+	}
+
+	const wait = time.Millisecond * 500
+	steps, err := (Config{StopSequence: jsonx.Strings{"TERM", wait.String(), "KILL"}}).stopSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exited := make(chan struct{})
+	startAt := time.Now()
+	go runStopSteps(slog.New(slog.DiscardHandler), c.Process, steps, exited)
+	c.Wait()
+	close(exited)
+
+	if elapsed := time.Since(startAt); elapsed < wait || elapsed > wait+time.Second*2 {
+		t.Errorf("停止耗时 %s，期望在 %s 后被 KILL", elapsed, wait)
+	}
+	if ws, ok := c.ProcessState.Sys().(syscall.WaitStatus); !ok || !ws.Signaled() || ws.Signal() != syscall.SIGKILL {
+		t.Errorf("进程未被 KILL 终止: %s", c.ProcessState)
+	}
+}
+
+// Signal 和 Reload 只通知进程，不重启
+func TestProgramSignal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	p := Start(ctx, Use(Config{
+		Path: "sh",
+		Args: []string{"-c", `trap "echo hup" HUP; trap "echo usr1" USR1; echo ready; while :; do sleep 0.05; done`},
+	}))
+	defer p.Stop()
+
+	waitLine := func(text string) {
+		for {
+			for _, l := range p.Tail(0) {
+				if l.Text == text {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("未读到输出 %q: %v", text, lineTexts(p.Tail(0)))
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}
+
+	waitLine("ready")
+	pid := p.Pid()
+
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	waitLine("hup")
+
+	if err := p.Signal(syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitLine("usr1")
+
+	if p.Pid() != pid || p.Status() != statusRunning {
+		t.Errorf("发送信号后进程被重启: pid %d -> %d, status=%s", pid, p.Pid(), p.Status())
+	}
+
+	p.Stop()
+	for p.Pid() != 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("程序未停止: status=%s", p.Status())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	if err := p.Signal(syscall.SIGUSR1); err == nil {
+		t.Error("程序未运行时发送信号未返回错误")
+	}
+}
@@ -0,0 +1,141 @@
+package cmdx
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	outputLines   = 1000     //默认保留的输出行数
+	outputLineMax = 64 << 10 //单行最大长度，超过后强制断行
+)
+
+// 输出流
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// Line 程序输出的一行
+type Line struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` //stdout, stderr
+	Text   string    `json:"text"`
+}
+
+// outputBuffer 保留最近的输出行，并分发给订阅者
+type outputBuffer struct {
+	lines []Line
+	start int
+	size  int
+	subs  hub[Line]
+	mu    sync.Mutex
+}
+
+// init 设置保留行数，n<0 时不保留，只分发
+func (b *outputBuffer) init(n int) {
+	if n == 0 {
+		n = outputLines
+	}
+	b.lines = make([]Line, max(n, 0))
+}
+
+func (b *outputBuffer) write(line Line) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n := len(b.lines); n > 0 {
+		b.lines[(b.start+b.size)%n] = line
+		if b.size < n {
+			b.size++
+		} else {
+			b.start = (b.start + 1) % n
+		}
+	}
+	b.subs.publish(line)
+}
+
+// tail 取得最近的 n 行，n<=0 时返回全部
+func (b *outputBuffer) tail(n int) []Line {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tailLocked(n)
+}
+
+func (b *outputBuffer) tailLocked(n int) []Line {
+	if n <= 0 || n > b.size {
+		n = b.size
+	}
+
+	lines := make([]Line, n)
+	for i := range n {
+		lines[i] = b.lines[(b.start+b.size-n+i)%len(b.lines)]
+	}
+	return lines
+}
+
+// subscribe 订阅输出，先推送最近的 backlog 行，再推送新的输出，中间不会遗漏或重复
+func (b *outputBuffer) subscribe(buffer, backlog int) (<-chan Line, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []Line
+	if backlog > 0 {
+		lines = b.tailLocked(backlog)
+	}
+	return b.subs.subscribe(buffer, lines...)
+}
+
+func (b *outputBuffer) close() { b.subs.close() }
+
+// writer 返回写入指定输出流的 io.Writer，dst 不为 nil 时同时写入 dst
+//
+// 返回的 flush 方法用于在进程退出后输出末尾不完整的行
+func (b *outputBuffer) writer(stream string, dst *os.File) (w io.Writer, flush func()) {
+	lw := &lineWriter{emit: func(text string) { b.write(Line{Time: time.Now(), Stream: stream, Text: text}) }}
+	if dst == nil {
+		return lw, lw.flush
+	}
+	return io.MultiWriter(dst, lw), lw.flush
+}
+
+// lineWriter 将写入的数据按行切分
+type lineWriter struct {
+	buf  []byte
+	emit func(text string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	rest := append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(rest[:i])
+		rest = rest[i+1:]
+	}
+	w.buf = append(w.buf[:0], rest...)
+
+	if len(w.buf) >= outputLineMax {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.line(w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+func (w *lineWriter) line(b []byte) {
+	if text := strings.TrimRight(string(b), "\r"); text != "" {
+		w.emit(text)
+	}
+}
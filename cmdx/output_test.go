@@ -0,0 +1,83 @@
+package cmdx
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func lineTexts(lines []Line) (texts []string) {
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	return
+}
+
+func TestOutputBuffer(t *testing.T) {
+	var b outputBuffer
+	b.init(3)
+	defer b.close()
+
+	for i := range 5 {
+		b.write(Line{Stream: StreamStdout, Text: fmt.Sprint(i)})
+	}
+
+	if got, want := lineTexts(b.tail(0)), []string{"2", "3", "4"}; !slices.Equal(got, want) {
+		t.Fatalf("环形缓冲区内容错误: got %v, want %v", got, want)
+	}
+	if got, want := lineTexts(b.tail(2)), []string{"3", "4"}; !slices.Equal(got, want) {
+		t.Fatalf("tail(2) 错误: got %v, want %v", got, want)
+	}
+	if got, want := lineTexts(b.tail(10)), []string{"2", "3", "4"}; !slices.Equal(got, want) {
+		t.Fatalf("tail(10) 错误: got %v, want %v", got, want)
+	}
+
+	//先推送 backlog，再推送新的输出
+	lines, cancel := b.subscribe(8, 2)
+	defer cancel()
+	b.write(Line{Stream: StreamStderr, Text: "5"})
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case l := <-lines:
+			got = append(got, l.Text)
+		case <-time.After(time.Second):
+			t.Fatalf("订阅未收到输出: %v", got)
+		}
+	}
+	if want := []string{"3", "4", "5"}; !slices.Equal(got, want) {
+		t.Fatalf("订阅内容错误: got %v, want %v", got, want)
+	}
+
+	//不保留时只分发
+	var none outputBuffer
+	none.init(-1)
+	defer none.close()
+	none.write(Line{Text: "x"})
+	if n := len(none.tail(0)); n != 0 {
+		t.Fatalf("不保留时 tail 返回了 %d 行", n)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var b outputBuffer
+	b.init(10)
+	defer b.close()
+
+	w, flush := b.writer(StreamStdout, nil)
+	for _, s := range []string{"he", "llo\r\nwor", "ld\n\n", "tail"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := lineTexts(b.tail(0)), []string{"hello", "world"}; !slices.Equal(got, want) {
+		t.Fatalf("按行切分错误: got %v, want %v", got, want)
+	}
+
+	flush()
+	if got, want := lineTexts(b.tail(0)), []string{"hello", "world", "tail"}; !slices.Equal(got, want) {
+		t.Fatalf("flush 错误: got %v, want %v", got, want)
+	}
+}
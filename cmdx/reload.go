@@ -0,0 +1,168 @@
+package cmdx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cnk3x/pkg/cmdo"
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/fsw"
+	"github.com/cnk3x/pkg/jsonx"
+	"github.com/cnk3x/pkg/x"
+	"github.com/fsnotify/fsnotify"
+)
+
+// 构建被取消后，等待构建命令退出的最长时间，超时后强制结束
+const buildWaitDelay = time.Second * 5
+
+// 有新的变化，正在进行的构建被取消
+var errBuildSuperseded = errors.New("cmdx: build superseded by new changes")
+
+// BuildResult 一次构建的结果
+type BuildResult struct {
+	Start    time.Time      `json:"start"`
+	Duration jsonx.Duration `json:"duration"`
+	Output   string         `json:"output,omitempty"` //构建命令的输出(stdout 和 stderr)
+	Err      error          `json:"-"`
+}
+
+// Reloader 开发时的自动重新加载: 文件变化后执行构建命令，成功后重启程序
+//
+//   - 构建期间有新的变化时，取消正在进行的构建，重新构建
+//   - 构建失败时保留正在运行的程序，通过日志和 OnBuild 报告错误
+//   - 程序运行中时调用 Restart 立即重启，不受重启策略的限制，未运行时调用 Start
+type Reloader struct {
+	program *Program
+	build   []string
+	options []cmdo.Option
+	onBuild func(result BuildResult)
+	log     *slog.Logger
+
+	cancel context.CancelCauseFunc
+	seq    uint64
+	mu     sync.Mutex
+}
+
+// ReloadOption 自动重新加载的选项
+type ReloadOption func(*Reloader)
+
+// BuildWith 设置构建命令的选项，如 cmdo.Dir、cmdo.Env
+func BuildWith(options ...cmdo.Option) ReloadOption {
+	return func(r *Reloader) { r.options = append(r.options, options...) }
+}
+
+// OnBuild 设置构建结束(成功或失败)的回调，被新的变化取消的构建不会回调
+func OnBuild(onBuild func(result BuildResult)) ReloadOption {
+	return func(r *Reloader) { r.onBuild = onBuild }
+}
+
+// ReloadLog 设置日志
+func ReloadLog(logger *slog.Logger) ReloadOption {
+	return func(r *Reloader) { r.log = logger }
+}
+
+// NewReloader 创建自动重新加载
+//
+// 参数:
+//   - program: 需要重启的程序
+//   - build: 构建命令及参数，为空时只重启程序
+//   - options: 选项
+func NewReloader(program *Program, build []string, options ...ReloadOption) *Reloader {
+	r := &Reloader{program: program, build: build, log: program.log}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Handle 处理文件变化，可作为 fsw.HandlerFunc 使用，构建完成并重启程序后返回
+func (r *Reloader) Handle(ctx context.Context, events []fsnotify.Event) {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel(errBuildSuperseded)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	r.seq++
+	seq := r.seq
+	r.cancel = cancel
+	r.mu.Unlock()
+	defer cancel(nil)
+
+	if len(events) > 0 {
+		r.log.Info("文件变化，重新构建", "file", events[0].Name, "count", len(events))
+	}
+
+	result := r.runBuild(ctx)
+	if errors.Is(context.Cause(ctx), errBuildSuperseded) {
+		r.log.Debug("构建已取消，等待新的构建")
+		return
+	}
+
+	if result.Err != nil {
+		r.log.Warn("构建失败，保留正在运行的程序", "err", result.Err, "output", result.Output)
+	} else if len(r.build) > 0 {
+		r.log.Info("构建成功", "duration", time.Duration(result.Duration))
+	}
+	if r.onBuild != nil {
+		r.onBuild(result)
+	}
+	if result.Err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq != r.seq {
+		return //构建完成时已经有新的构建开始
+	}
+
+	switch r.program.Status() {
+	case statusStarting, statusRunning, statusRestarting, statusUnhealthy:
+		x.Ig(r.program.Restart())
+	default:
+		x.Ig(r.program.Start())
+	}
+}
+
+// runBuild 执行构建命令
+func (r *Reloader) runBuild(ctx context.Context) (result BuildResult) {
+	result.Start = time.Now()
+	if len(r.build) == 0 {
+		return
+	}
+
+	var output bytes.Buffer
+	c := exec.CommandContext(ctx, r.build[0], r.build[1:]...)
+	c.Stdout, c.Stderr = &output, &output
+	cmdo.Apply(c, cmdo.With(r.options...), cmdo.PKill)
+	//取消时终止整个进程组，不读取 ProcessState，避免与 Wait 竞争
+	c.Cancel = func() error { return cmdo.Signal(c.Process, syscall.SIGTERM) }
+	c.WaitDelay = buildWaitDelay
+
+	if err := c.Run(); err != nil {
+		result.Err = errx.Errorf("cmdx: build: %w", err)
+	}
+	result.Duration = jsonx.Duration(time.Since(result.Start))
+	result.Output = output.String()
+	return
+}
+
+// Watch 监听文件变化并自动重新加载，此方法会阻塞直到 ctx 结束
+//
+// 参数:
+//   - options: 监听选项
+//   - routeOptions: 匹配规则等路由选项，如 fsw.Glob("*.go")
+func (r *Reloader) Watch(ctx context.Context, options fsw.Options, routeOptions ...fsw.HandlerOption) (err error) {
+	w := fsw.New(options)
+	w.Handle("reload", append(routeOptions, fsw.Handle(r.Handle))...)
+	if err = w.Run(ctx); ctx.Err() != nil {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,61 @@
+package cmdx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestReloader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	//默认的重启策略不自动重启，Reloader 仍然可以重启程序
+	p := Start(ctx, Use(Config{Path: "sleep", Args: []string{"30"}}))
+	defer p.Stop()
+
+	waitPid := func(not int) int {
+		for ctx.Err() == nil {
+			if pid := p.Pid(); pid != 0 && pid != not {
+				return pid
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("程序未启动")
+		return 0
+	}
+	pid := waitPid(0)
+
+	var results []BuildResult
+	changes := []fsnotify.Event{{Name: "main.go", Op: fsnotify.Write}}
+
+	//构建失败，保留正在运行的程序
+	NewReloader(p, []string{"sh", "-c", "echo broken; exit 1"}, OnBuild(func(r BuildResult) { results = append(results, r) })).Handle(ctx, changes)
+	if len(results) != 1 || results[0].Err == nil || results[0].Output != "broken\n" {
+		t.Fatalf("构建失败未报告: %+v", results)
+	}
+	if p.Pid() != pid {
+		t.Fatal("构建失败后程序被重启")
+	}
+
+	//新的变化取消正在进行的构建
+	flag := filepath.Join(t.TempDir(), "flag")
+	r := NewReloader(p, []string{"sh", "-c", "test -f " + flag + " || sleep 5"}, OnBuild(func(r BuildResult) { results = append(results, r) }))
+	done := make(chan struct{})
+	go func() { defer close(done); r.Handle(ctx, changes) }()
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(flag, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.Handle(ctx, changes)
+	<-done
+	if len(results) != 2 || results[1].Err != nil {
+		t.Fatalf("构建结果错误: %+v", results)
+	}
+
+	waitPid(pid)
+}
@@ -0,0 +1,351 @@
+package cmdx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/logx"
+	"github.com/cnk3x/pkg/x"
+)
+
+// 等待依赖程序启动的最长时间
+const dependsWait = time.Second * 10
+
+// Supervisor 管理多个程序，按依赖顺序(depends_on)启动，按相反顺序停止
+type Supervisor struct {
+	configs map[string]Config
+	options []Option
+	log     *slog.Logger
+
+	programs map[string]*supervised
+	order    []string
+	ctx      context.Context
+	mu       sync.Mutex
+
+	//串行执行 Start、Stop、Reload，等待程序启动或退出期间不持有 mu，不影响 Status 等查询
+	op sync.Mutex
+}
+
+type supervised struct {
+	*Program
+	cancel context.CancelFunc
+}
+
+// NewSupervisor 创建一个多程序管理器
+//
+// 参数:
+//   - configs: 程序配置，键为程序名称
+//   - options: 应用到每一个程序的选项
+//
+// 返回值: 返回 Supervisor 指针，调用 Start 后开始运行
+func NewSupervisor(configs map[string]Config, options ...Option) *Supervisor {
+	return &Supervisor{
+		configs:  maps.Clone(configs),
+		options:  options,
+		log:      logx.With("管理"),
+		programs: map[string]*supervised{},
+	}
+}
+
+// Start 按依赖顺序启动所有程序，被依赖的程序进入运行状态(或超时)后才会启动下一个
+func (s *Supervisor) Start(ctx context.Context) (err error) {
+	s.op.Lock()
+	defer s.op.Unlock()
+
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return errx.Errorf("cmdx: supervisor already started")
+	}
+
+	order, err := dependsOrder(s.configs)
+	if err == nil {
+		err = checkConfigs(s.configs)
+	}
+	if err == nil {
+		s.order, s.ctx = order, ctx
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return
+	}
+	return s.startMatched(func(string) bool { return true })
+}
+
+// Stop 按依赖的相反顺序停止所有程序，并等待其退出
+func (s *Supervisor) Stop() {
+	s.op.Lock()
+	defer s.op.Unlock()
+
+	s.stopMatched(func(string) bool { return true })
+
+	s.mu.Lock()
+	s.order, s.ctx = nil, nil
+	s.mu.Unlock()
+}
+
+// Reload 使用新的配置更新程序: 新增的程序启动，删除的程序停止，定义发生变化的程序按新配置重新启动，未变化的程序不受影响
+//
+// 新配置存在循环依赖、未知依赖或无效的停止序列时返回错误，正在运行的程序保持不变
+func (s *Supervisor) Reload(configs map[string]Config) (err error) {
+	s.op.Lock()
+	defer s.op.Unlock()
+
+	order, err := dependsOrder(configs)
+	if err != nil {
+		return
+	}
+	if err = checkConfigs(configs); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	prev, started := s.configs, s.ctx != nil
+	if !started {
+		s.configs, s.order = maps.Clone(configs), order
+	}
+	s.mu.Unlock()
+
+	added, removed, changed := diffConfigs(prev, configs)
+	for _, name := range added {
+		s.log.Info("新增程序", "name", name)
+	}
+	for _, name := range removed {
+		s.log.Info("删除程序", "name", name)
+	}
+	for name, fields := range changed {
+		s.log.Info("程序配置变更", "name", name, "fields", fields)
+	}
+
+	if !started {
+		return
+	}
+
+	affected := func(name string) bool {
+		_, ok := changed[name]
+		return ok || slices.Contains(added, name) || slices.Contains(removed, name)
+	}
+
+	s.stopMatched(affected)
+
+	s.mu.Lock()
+	s.configs, s.order = maps.Clone(configs), order
+	s.mu.Unlock()
+
+	return s.startMatched(affected)
+}
+
+// startMatched 按依赖顺序启动 match 匹配且未运行的程序，需持有 op
+func (s *Supervisor) startMatched(match func(name string) bool) error {
+	s.mu.Lock()
+	order, ctx := slices.Clone(s.order), s.ctx
+	s.mu.Unlock()
+
+	for _, name := range order {
+		select {
+		case <-ctx.Done():
+			return errx.Errorf("cmdx: %w", context.Cause(ctx))
+		default:
+		}
+
+		s.mu.Lock()
+		p, running := s.programs[name]
+		if !running && match(name) {
+			s.log.Debug("启动程序", "name", name)
+			p = s.spawn(ctx, name, s.configs[name])
+			s.programs[name] = p
+		}
+		wait := !running && match(name) && len(s.dependents(name)) > 0
+		s.mu.Unlock()
+
+		//等待期间不持有锁
+		if wait {
+			if st := p.waitStarted(ctx, dependsWait); st != statusRunning {
+				s.log.Warn("被依赖的程序未能进入运行状态", "name", name, "status", st)
+			}
+		}
+	}
+	return nil
+}
+
+// stopMatched 按依赖的相反顺序停止 match 匹配的程序，并等待其退出，需持有 op
+func (s *Supervisor) stopMatched(match func(name string) bool) {
+	s.mu.Lock()
+	order := slices.Clone(s.order)
+	s.mu.Unlock()
+
+	for _, name := range slices.Backward(order) {
+		s.mu.Lock()
+		p, ok := s.programs[name]
+		s.mu.Unlock()
+
+		if ok && match(name) {
+			s.log.Debug("停止程序", "name", name)
+			p.cancel()
+			<-p.Done()
+
+			s.mu.Lock()
+			delete(s.programs, name)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Names 返回程序名称，按启动顺序排列
+func (s *Supervisor) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.order)
+}
+
+// Program 根据名称取得程序，不存在返回 nil
+func (s *Supervisor) Program(name string) *Program {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.programs[name]; ok {
+		return p.Program
+	}
+	return nil
+}
+
+// Status 取得所有程序的状态，键为程序名称
+func (s *Supervisor) Status() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := make(map[string]string, len(s.programs))
+	for name, p := range s.programs {
+		status[name] = p.Status()
+	}
+	return status
+}
+
+func (s *Supervisor) spawn(ctx context.Context, name string, cfg Config) *supervised {
+	pctx, cancel := context.WithCancel(ctx)
+	options := append([]Option{Use(cfg), Log(logx.With(name))}, s.options...)
+	return &supervised{Program: Start(pctx, options...), cancel: cancel}
+}
+
+// dependents 返回直接依赖 name 的程序名称
+func (s *Supervisor) dependents(name string) (names []string) {
+	for n, cfg := range s.configs {
+		if slices.Contains(cfg.DependsOn, name) {
+			names = append(names, n)
+		}
+	}
+	return
+}
+
+// waitStarted 等待程序离开启动状态，返回最终观察到的状态
+func (p *Program) waitStarted(ctx context.Context, timeout time.Duration) string {
+	deadline := time.After(timeout)
+	for {
+		if st := p.Status(); st != "" && st != statusStarting {
+			return st
+		}
+		select {
+		case <-ctx.Done():
+			return p.Status()
+		case <-p.Done():
+			return p.Status()
+		case <-deadline:
+			return p.Status()
+		case <-time.After(time.Millisecond * 50):
+		}
+	}
+}
+
+// dependsOrder 根据 depends_on 计算启动顺序，同一层级按名称排序，存在循环依赖或未知依赖时返回错误
+func dependsOrder(configs map[string]Config) (order []string, err error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(configs))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return errx.Errorf("cmdx: circular dependency: %v", append(path, name))
+		}
+
+		cfg, ok := configs[name]
+		if !ok {
+			return errx.Errorf("cmdx: unknown dependency %q of %q", name, path[len(path)-1])
+		}
+
+		state[name] = visiting
+		deps := slices.Sorted(slices.Values(cfg.DependsOn))
+		for _, dep := range deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		if err = visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// diffConfigs 比较新旧配置，changed 的值为发生变化的字段(json 名称)
+func diffConfigs(prev, next map[string]Config) (added, removed []string, changed map[string][]string) {
+	changed = map[string][]string{}
+	for _, name := range slices.Sorted(maps.Keys(next)) {
+		o, ok := prev[name]
+		if !ok {
+			added = append(added, name)
+			continue
+		}
+		if fields := configFieldsDiff(o, next[name]); len(fields) > 0 {
+			changed[name] = fields
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(prev)) {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return
+}
+
+// configFieldsDiff 以 json 形式比较两个配置，返回不同的字段名称
+func configFieldsDiff(a, b Config) (fields []string) {
+	var ma, mb map[string]json.RawMessage
+	if da, e := json.Marshal(a); e == nil {
+		x.Ig(json.Unmarshal(da, &ma))
+	}
+	if db, e := json.Marshal(b); e == nil {
+		x.Ig(json.Unmarshal(db, &mb))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(ma)) {
+		if vb, ok := mb[k]; !ok || !bytes.Equal(ma[k], vb) {
+			fields = append(fields, k)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(mb)) {
+		if _, ok := ma[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	return
+}
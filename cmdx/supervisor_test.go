@@ -0,0 +1,54 @@
+package cmdx
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDependsOrder(t *testing.T) {
+	order, err := dependsOrder(map[string]Config{
+		"web":   {DependsOn: []string{"db", "cache"}},
+		"db":    {},
+		"cache": {DependsOn: []string{"db"}},
+		"cron":  {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("启动顺序: %v", order)
+
+	if want := []string{"db", "cache", "cron", "web"}; !slices.Equal(order, want) {
+		t.Fatalf("启动顺序错误: got %v, want %v", order, want)
+	}
+
+	if _, err = dependsOrder(map[string]Config{"a": {DependsOn: []string{"b"}}, "b": {DependsOn: []string{"a"}}}); err == nil {
+		t.Fatal("循环依赖未报错")
+	}
+
+	if _, err = dependsOrder(map[string]Config{"a": {DependsOn: []string{"x"}}}); err == nil {
+		t.Fatal("未知依赖未报错")
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	prev := map[string]Config{
+		"a": {Path: "/bin/a"},
+		"b": {Path: "/bin/b", Args: []string{"-v"}},
+		"c": {Path: "/bin/c"},
+	}
+	next := map[string]Config{
+		"a": {Path: "/bin/a"},
+		"b": {Path: "/bin/b", Args: []string{"-vv"}},
+		"d": {Path: "/bin/d"},
+	}
+
+	added, removed, changed := diffConfigs(prev, next)
+	t.Logf("added: %v, removed: %v, changed: %v", added, removed, changed)
+
+	if !slices.Equal(added, []string{"d"}) || !slices.Equal(removed, []string{"c"}) {
+		t.Fatalf("新增或删除错误: added %v, removed %v", added, removed)
+	}
+	if len(changed) != 1 || !slices.Equal(changed["b"], []string{"args"}) {
+		t.Fatalf("变更错误: %v", changed)
+	}
+}
@@ -0,0 +1,74 @@
+package cmdx
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/cnk3x/pkg/configx"
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/fsw"
+	"github.com/fsnotify/fsnotify"
+)
+
+// LoadConfigs 从 json/yaml 文件中加载程序配置，文件内容为以程序名称为键的对象
+func LoadConfigs(file string) (configs map[string]Config, err error) {
+	if err = configx.UnmarshalFile(&configs, file); err != nil {
+		return nil, errx.Errorf("cmdx: load %s: %w", file, err)
+	}
+	if err = checkConfigs(configs); err != nil {
+		return nil, errx.Errorf("cmdx: load %s: %w", file, err)
+	}
+	return
+}
+
+// checkConfigs 检查只在运行时才会解析的配置(停止序列)，避免配置错误时程序按重启策略反复启动失败
+func checkConfigs(configs map[string]Config) error {
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		if _, err := configs[name].stopSteps(); err != nil {
+			return errx.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WatchFile 从文件加载配置并启动所有程序，然后监听文件变化，变化后重新加载配置并调用 Reload
+//
+// 此方法会阻塞直到 ctx 结束，应代替 Start 调用，重新加载失败或新配置无效时保持正在运行的程序不变
+func (s *Supervisor) WatchFile(ctx context.Context, file string) (err error) {
+	if file, err = filepath.Abs(file); err != nil {
+		return
+	}
+
+	configs, err := LoadConfigs(file)
+	if err != nil {
+		return
+	}
+
+	if err = s.Reload(configs); err != nil {
+		return
+	}
+
+	if err = s.Start(ctx); err != nil {
+		return
+	}
+
+	w := fsw.New(fsw.Options{Root: []string{filepath.Dir(file)}, Event: "cwm", NonRecursive: true})
+	w.Handle("config", fsw.Match(`(^|/)`+regexp.QuoteMeta(filepath.Base(file))+`$`), fsw.Handle(func(ctx context.Context, _ []fsnotify.Event) {
+		s.log.Info("配置文件变更，重新加载", "file", file)
+		configs, err := LoadConfigs(file)
+		if err == nil {
+			err = s.Reload(configs)
+		}
+		if err != nil {
+			s.log.Warn("重新加载配置失败", "file", file, "err", err)
+		}
+	}))
+
+	if err = w.Run(ctx); ctx.Err() != nil {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package cron
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+const dateLayout = time.DateOnly
+
+// Calendar 排除日历，保存需要跳过的日期(节假日等)
+type Calendar struct {
+	dates map[string]struct{} //2006-01-02
+	mu    sync.RWMutex
+}
+
+// NewCalendar 创建排除日历
+//
+// 参数:
+//   - dates: 排除的日期，格式为 2006-01-02，或 2006-01-02/2006-01-07 表示的范围(含两端)
+func NewCalendar(dates ...string) (*Calendar, error) {
+	c := &Calendar{dates: map[string]struct{}{}}
+	for _, d := range dates {
+		if err := c.AddString(d); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadCalendar 从文件加载排除日历，.ics 文件按 iCalendar 解析，其他按 json 字符串数组解析
+func LoadCalendar(file string) (*Calendar, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errx.Errorf("cron: load calendar: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".ics") {
+		return ParseICal(bytes.NewReader(data))
+	}
+
+	var dates jsonx.Strings
+	if err = jsonx.Unmarshal(data, &dates); err != nil {
+		return nil, errx.Errorf("cron: load calendar %s: %w", file, err)
+	}
+	return NewCalendar(dates...)
+}
+
+// ParseICal 解析 iCalendar，VEVENT 的 DTSTART 至 DTEND(不含) 之间的日期都会被排除，不支持 RRULE
+func ParseICal(r io.Reader) (*Calendar, error) {
+	c, _ := NewCalendar()
+
+	var (
+		inEvent    bool
+		start, end time.Time
+	)
+
+	for line := range icalLines(r) {
+		name, value, _ := strings.Cut(line, ":")
+		name, _, _ = strings.Cut(name, ";")
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end = true, time.Time{}, time.Time{}
+			}
+		case "END":
+			if strings.EqualFold(value, "VEVENT") && inEvent {
+				inEvent = false
+				if start.IsZero() {
+					continue
+				}
+				if !end.After(start) {
+					end = start.AddDate(0, 0, 1)
+				}
+				for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+					c.Add(d)
+				}
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			if len(value) < 8 {
+				return nil, errx.Errorf("cron: invalid ical date: %s", line)
+			}
+			d, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, errx.Errorf("cron: invalid ical date: %s: %w", line, err)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				start = d
+			} else {
+				end = d
+			}
+		}
+	}
+	return c, nil
+}
+
+// icalLines 按行读取 iCalendar，并合并折叠的行(以空格或制表符开头的行是上一行的延续)
+func icalLines(r io.Reader) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		var (
+			scanner = bufio.NewScanner(r)
+			line    string
+		)
+		for scanner.Scan() {
+			text := strings.TrimRight(scanner.Text(), "\r")
+			if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+				line += text[1:]
+				continue
+			}
+			if line != "" && !yield(line) {
+				return
+			}
+			line = text
+		}
+		if line != "" {
+			yield(line)
+		}
+	}
+}
+
+// Add 添加排除的日期，按 t 所在时区的日期计算
+func (c *Calendar) Add(dates ...time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range dates {
+		c.dates[d.Format(dateLayout)] = struct{}{}
+	}
+}
+
+// AddString 添加排除的日期，格式为 2006-01-02，或 2006-01-02/2006-01-07 表示的范围(含两端)
+func (c *Calendar) AddString(s string) error {
+	from, to, isRange := strings.Cut(strings.TrimSpace(s), "/")
+
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return fmt.Errorf("invalid date: %q", s)
+	}
+
+	end := start
+	if isRange {
+		if end, err = time.Parse(dateLayout, to); err != nil || end.Before(start) {
+			return fmt.Errorf("invalid date range: %q", s)
+		}
+	}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		c.Add(d)
+	}
+	return nil
+}
+
+// Excluded 判断 t 所在时区的日期是否被排除
+func (c *Calendar) Excluded(t time.Time) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.dates[t.Format(dateLayout)]
+	return ok
+}
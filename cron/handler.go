@@ -0,0 +1,157 @@
+package cron
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/webx"
+	"github.com/cnk3x/pkg/webx/respond"
+)
+
+// JobInfo 任务信息
+type JobInfo struct {
+	Entry
+	Stats   Stats `json:"stats"`
+	History []Run `json:"history,omitempty"`
+}
+
+// Job 取得任务信息，history 为 true 时包含执行记录
+func (s *Scheduler) Job(name string, history bool) (info JobInfo, ok bool) {
+	if info.Entry, ok = s.Entry(name); !ok {
+		return
+	}
+	info.Stats, _ = s.Stats(name)
+	if history {
+		info.History = s.History(name)
+	}
+	return
+}
+
+// Handler 计划任务接口，可使用 webx.Strip 挂载到任意前缀下
+//
+//	GET  /               列出所有任务及下次执行时间
+//	GET  /metrics        Prometheus 文本格式的统计
+//	GET  /{name}         取得任务信息及执行记录
+//	POST /{name}/trigger 立即执行任务
+//
+// 名称为 metrics 的任务只能通过列表查看
+func Handler(s *Scheduler) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /{$}", webx.Handle(func(ctx context.Context, _ *struct{}) (list []JobInfo, err error) {
+		list = []JobInfo{}
+		for _, e := range s.Entries() {
+			info, _ := s.Job(e.Name, false)
+			list = append(list, info)
+		}
+		return
+	}))
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = s.WriteMetrics(w)
+	})
+
+	mux.Handle("GET /{name}", withJob(s, webx.Handle(func(ctx context.Context, _ *struct{}) (info JobInfo, err error) {
+		name, _ := ctx.Value(pathNameKey).(string)
+		info, _ = s.Job(name, true)
+		return
+	})))
+
+	mux.Handle("POST /{name}/trigger", withJob(s, webx.Handle(func(ctx context.Context, _ *struct{}) (info JobInfo, err error) {
+		name, _ := ctx.Value(pathNameKey).(string)
+		if err = s.Trigger(name); err == nil {
+			info, _ = s.Job(name, false)
+		}
+		return
+	})))
+
+	return mux
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出任务统计
+//
+//	cron_job_runs_total{job,outcome}          各执行结果的次数
+//	cron_job_run_seconds_total{job}           累计执行时长
+//	cron_job_running{job}                     正在执行的数量
+//	cron_job_last_success_timestamp_seconds   最近一次成功结束的时间
+//	cron_job_next_run_timestamp_seconds       下次执行时间
+func (s *Scheduler) WriteMetrics(w io.Writer) error {
+	type metric struct {
+		name, help, kind string
+		values           []string
+	}
+
+	var (
+		runs    = metric{name: "cron_job_runs_total", help: "Total number of job runs by outcome.", kind: "counter"}
+		seconds = metric{name: "cron_job_run_seconds_total", help: "Total time spent running the job.", kind: "counter"}
+		running = metric{name: "cron_job_running", help: "Number of runs currently in progress.", kind: "gauge"}
+		success = metric{name: "cron_job_last_success_timestamp_seconds", help: "Unix time of the last successful run.", kind: "gauge"}
+		next    = metric{name: "cron_job_next_run_timestamp_seconds", help: "Unix time of the next scheduled run.", kind: "gauge"}
+	)
+
+	entries := s.Entries()
+	slices.SortFunc(entries, func(a, b Entry) int { return cmp.Compare(a.Name, b.Name) })
+
+	for _, e := range entries {
+		stats, ok := s.Stats(e.Name)
+		if !ok {
+			continue
+		}
+
+		job := `job="` + escapeLabel(e.Name) + `"`
+		for _, outcome := range slices.Sorted(maps.Keys(stats.Runs)) {
+			runs.values = append(runs.values, fmt.Sprintf("{%s,outcome=%q} %d", job, outcome, stats.Runs[outcome]))
+		}
+		seconds.values = append(seconds.values, fmt.Sprintf("{%s} %s", job, formatFloat(time.Duration(stats.Duration).Seconds())))
+		running.values = append(running.values, fmt.Sprintf("{%s} %d", job, e.Running))
+		if !stats.LastSuccess.IsZero() {
+			success.values = append(success.values, fmt.Sprintf("{%s} %d", job, stats.LastSuccess.Unix()))
+		}
+		if !e.Next.IsZero() {
+			next.values = append(next.values, fmt.Sprintf("{%s} %d", job, e.Next.Unix()))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []metric{runs, seconds, running, success, next} {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, v := range m.values {
+			fmt.Fprintf(bw, "%s%s\n", m.name, v)
+		}
+	}
+	return bw.Flush()
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+type pathKey struct{ name string }
+
+var pathNameKey = &pathKey{"name"}
+
+// withJob 将路径参数 name 放入上下文，供 webx.Handle 的处理函数读取，任务不存在时返回 404
+func withJob(s *Scheduler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if _, ok := s.Entry(name); !ok {
+			respond.Status(r, http.StatusNotFound)
+			respond.Respond(w, r, respond.E(errx.Errorf("cron: job %q not found", name), "NOT_FOUND"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathNameKey, name)))
+	})
+}
@@ -0,0 +1,115 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	s := NewScheduler(discard)
+	if err := s.Add("@every 1h", "ok", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("@every 1h", `a"b`, func(ctx context.Context) error { return errors.New("fail") }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ok", `a"b`, "ok"} {
+		if err := s.Trigger(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Stop() //等待执行结束
+
+	var buf bytes.Buffer
+	if err := s.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	stats, _ := s.Stats("ok")
+	for _, want := range []string{
+		"# HELP cron_job_runs_total Total number of job runs by outcome.\n# TYPE cron_job_runs_total counter\n",
+		`cron_job_runs_total{job="a\"b",outcome="failed"} 1` + "\n",
+		`cron_job_runs_total{job="ok",outcome="success"} 2` + "\n",
+		`cron_job_running{job="ok"} 0` + "\n",
+		fmt.Sprintf(`cron_job_last_success_timestamp_seconds{job="ok"} %d`+"\n", stats.LastSuccess.Unix()),
+		"# TYPE cron_job_next_run_timestamp_seconds gauge\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出缺少 %q:\n%s", want, out)
+		}
+	}
+
+	//失败的任务没有成功时间
+	if strings.Contains(out, `cron_job_last_success_timestamp_seconds{job="a\"b"}`) {
+		t.Errorf("失败的任务不应输出成功时间:\n%s", out)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := NewScheduler(discard)
+	if err := s.Add("@every 1h", "job", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	srv := httptest.NewServer(Handler(s))
+	defer srv.Close()
+
+	do := func(method, path string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(t.Context(), method, srv.URL+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return resp, body.Bytes()
+	}
+
+	resp, body := do(http.MethodGet, "/")
+	var list []JobInfo
+	if err := json.Unmarshal(body, &list); err != nil || len(list) != 1 || list[0].Name != "job" || list[0].Next.IsZero() {
+		t.Fatalf("任务列表错误: %d %s %v", resp.StatusCode, body, err)
+	}
+
+	if resp, body = do(http.MethodPost, "/job/trigger"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("触发任务失败: %d %s", resp.StatusCode, body)
+	}
+	waitFor(t, time.Second, "手动执行", func() bool { return len(s.History("job")) == 1 })
+
+	resp, body = do(http.MethodGet, "/job")
+	var info JobInfo
+	if err := json.Unmarshal(body, &info); err != nil || len(info.History) != 1 || info.History[0].Trigger != TriggerManual {
+		t.Fatalf("任务信息错误: %d %s %v", resp.StatusCode, body, err)
+	}
+
+	if resp, body = do(http.MethodGet, "/missing"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("不存在的任务应返回 404: %d %s", resp.StatusCode, body)
+	}
+	if resp, body = do(http.MethodPost, "/missing/trigger"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("触发不存在的任务应返回 404: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = do(http.MethodGet, "/metrics")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") || !bytes.Contains(body, []byte(`cron_job_runs_total{job="job",outcome="success"} 1`)) {
+		t.Fatalf("metrics 错误: %s\n%s", ct, body)
+	}
+}
@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// Locker 多个进程(副本)之间的执行锁，保证同一个任务在同一个计划时间只执行一次
+//
+// 取得执行权的条件: 上次记录的计划时间早于 tick，且上次的租约已过期
+type Locker interface {
+	// Acquire 尝试取得任务在 tick 的执行权，已被其他进程取得时返回 false
+	Acquire(ctx context.Context, name string, tick time.Time) (bool, error)
+}
+
+// Lock 设置执行锁，每次执行前都需要先取得执行权
+func Lock(locker Locker) SchedulerOption {
+	return func(s *Scheduler) { s.locker = locker }
+}
+
+// Lease 执行锁记录的内容
+type Lease struct {
+	Tick    time.Time `json:"tick"`    //取得执行权的计划时间
+	Owner   string    `json:"owner"`   //取得执行权的进程，默认为 主机名:pid
+	Expires time.Time `json:"expires"` //租约到期时间，到期前其他进程不能取得执行权
+}
+
+// Available 判断给定的 tick 在 now 时刻能否取得执行权
+func (l Lease) Available(tick, now time.Time) bool {
+	return l.Tick.Before(tick) && !now.Before(l.Expires)
+}
+
+// Owner 返回当前进程的标识: 主机名:pid
+func Owner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// FileLocker 使用文件锁(flock)实现的执行锁，适用于共享同一个文件系统的多个进程
+type FileLocker struct {
+	dir   string
+	lease time.Duration
+	owner string
+}
+
+// NewFileLocker 创建文件执行锁，每个任务在 dir 下对应一个 .lock 文件
+//
+// 参数:
+//   - dir: 锁文件所在目录，不存在时自动创建
+//   - lease: 租约时长，使用 Jitter 时各副本的计划时间不同，应设置为不小于抖动的最大值
+func NewFileLocker(dir string, lease time.Duration) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errx.Errorf("cron: locker: %w", err)
+	}
+	return &FileLocker{dir: dir, lease: lease, owner: Owner()}, nil
+}
+
+// Acquire 尝试取得任务在 tick 的执行权
+func (l *FileLocker) Acquire(_ context.Context, name string, tick time.Time) (ok bool, err error) {
+	f, err := os.OpenFile(filepath.Join(l.dir, url.PathEscape(name)+".lock"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, errx.Errorf("cron: locker: %w", err)
+	}
+	defer f.Close()
+
+	if err = lockFile(f); err != nil {
+		return false, errx.Errorf("cron: locker: %w", err)
+	}
+	defer unlockFile(f)
+
+	var last Lease
+	if data, _ := io.ReadAll(f); len(data) > 0 {
+		if err = jsonx.Unmarshal(data, &last); err != nil {
+			return false, errx.Errorf("cron: locker: %s: %w", f.Name(), err)
+		}
+	}
+
+	now := time.Now()
+	if !last.Available(tick, now) {
+		return false, nil
+	}
+
+	data, _ := jsonx.Marshal(Lease{Tick: tick, Owner: l.owner, Expires: now.Add(l.lease)})
+	if err = f.Truncate(0); err == nil {
+		if _, err = f.WriteAt(data, 0); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		return false, errx.Errorf("cron: locker: %w", err)
+	}
+	return true, nil
+}
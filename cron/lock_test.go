@@ -0,0 +1,48 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLocker(t *testing.T) {
+	dir := t.TempDir()
+	tick := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var (
+		acquired atomic.Int32
+		wg       sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := NewFileLocker(dir, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok, err := l.Acquire(context.Background(), "job/a", tick); err != nil {
+				t.Error(err)
+			} else if ok {
+				acquired.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := acquired.Load(); n != 1 {
+		t.Fatalf("acquired %d times, want 1", n)
+	}
+
+	l, _ := NewFileLocker(dir, time.Hour)
+	if ok, _ := l.Acquire(context.Background(), "job/a", tick.Add(time.Minute)); !ok {
+		t.Fatal("next tick should be acquired")
+	}
+	if ok, _ := l.Acquire(context.Background(), "job/a", tick.Add(time.Minute*2)); ok {
+		t.Fatal("lease should not be expired")
+	}
+}
@@ -0,0 +1,379 @@
+package cron
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+	"github.com/cnk3x/pkg/logx"
+)
+
+// JobFunc 计划任务的执行函数
+type JobFunc func(ctx context.Context) error
+
+// Entry 计划任务的快照
+type Entry struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec,omitempty"`    //计划表达式，使用 AddSchedule 添加时为空
+	Overlap Overlap   `json:"overlap"`           //上次执行未结束时的处理策略
+	Next    time.Time `json:"next,omitzero"`     //下次执行时间，调度器未启动或计划不会再触发时为零值
+	Prev    time.Time `json:"prev,omitzero"`     //上次执行时间，尚未执行时为零值
+	Running int       `json:"running,omitempty"` //正在执行的数量
+	Pending bool      `json:"pending,omitempty"` //是否有排队等待的执行
+}
+
+// Scheduler 计划任务调度器，按 Schedule 计算的时间执行任务
+type Scheduler struct {
+	parser ScheduleParser
+	loc    *time.Location
+	log    *slog.Logger
+	store  Store
+	locker Locker
+
+	entries map[string]*entry
+	wake    chan struct{}
+	ctx     context.Context //Start 传入的上下文，任务在此上下文中执行
+	stop    context.CancelFunc
+	done    chan struct{}
+	jobs    sync.WaitGroup
+	mu      sync.Mutex
+}
+
+type entry struct {
+	name     string
+	spec     string
+	schedule Schedule
+	job      JobFunc
+	overlap  Overlap
+	keep     int
+
+	catchUp      CatchUp
+	catchUpLimit int
+
+	next        time.Time
+	prev        time.Time
+	running     int
+	pending     bool      //OverlapQueue: 有一次排队等待的执行
+	pendingTick time.Time //排队的执行对应的计划时间
+	cancels     map[uint64]context.CancelCauseFunc
+	seq         uint64
+	history     []Run
+	stats       Stats
+}
+
+// Stats 任务的执行统计，从任务添加时开始累计
+type Stats struct {
+	Runs        map[string]uint64 `json:"runs"`                  //各执行结果的次数
+	Duration    jsonx.Duration    `json:"duration"`              //累计执行时长
+	LastSuccess time.Time         `json:"last_success,omitzero"` //最近一次成功结束的时间
+}
+
+// SchedulerOption 调度器选项
+type SchedulerOption func(*Scheduler)
+
+// SpecParser 设置计划表达式解析器，默认支持可选的秒字段和描述符
+func SpecParser(parser ScheduleParser) SchedulerOption {
+	return func(s *Scheduler) { s.parser = parser }
+}
+
+// Location 设置调度器使用的时区，默认为 time.Local，表达式中指定的时区(TZ=)优先
+func Location(loc *time.Location) SchedulerOption {
+	return func(s *Scheduler) { s.loc = loc }
+}
+
+// Log 设置日志
+func Log(logger *slog.Logger) SchedulerOption {
+	return func(s *Scheduler) { s.log = logger }
+}
+
+// NewScheduler 创建计划任务调度器
+func NewScheduler(options ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		parser:  New(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor),
+		loc:     time.Local,
+		log:     logx.With("计划"),
+		entries: map[string]*entry{},
+		wake:    make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Add 解析计划表达式并添加任务，名称不能重复
+//
+// 参数:
+//   - spec: 计划表达式，如 "*/5 * * * *"、"@every 1m"
+//   - name: 任务名称
+//   - job: 任务执行函数
+//   - options: 任务选项，如 WithOverlap、KeepHistory
+//
+// 返回值: 表达式无效或名称重复时返回错误
+func (s *Scheduler) Add(spec, name string, job JobFunc, options ...EntryOption) error {
+	schedule, err := s.parser.Parse(spec)
+	if err != nil {
+		return errx.Errorf("cron: %s: %w", name, err)
+	}
+
+	//表达式未指定时区(TZ=, CRON_TZ=)时使用调度器的时区
+	if ss, ok := schedule.(*SpecSchedule); ok && !strings.Contains(spec, "TZ=") {
+		ss.Location = s.loc
+	}
+	return s.add(&entry{name: name, spec: spec, schedule: schedule, job: job}, options)
+}
+
+// AddSchedule 使用已有的 Schedule 添加任务，名称不能重复
+func (s *Scheduler) AddSchedule(schedule Schedule, name string, job JobFunc, options ...EntryOption) error {
+	return s.add(&entry{name: name, schedule: schedule, job: job}, options)
+}
+
+func (s *Scheduler) add(e *entry, options []EntryOption) error {
+	e.overlap, e.keep, e.cancels = OverlapAllow, historyKeep, map[uint64]context.CancelCauseFunc{}
+	e.stats.Runs = map[string]uint64{}
+	for _, option := range options {
+		option(e)
+	}
+
+	if e.name == "" || e.job == nil || e.schedule == nil {
+		return errx.Errorf("cron: name, job and schedule are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[e.name]; exists {
+		return errx.Errorf("cron: job %q already exists", e.name)
+	}
+
+	s.entries[e.name] = e
+	if s.stop != nil {
+		s.schedule(e, s.now())
+	}
+	s.notify()
+	return nil
+}
+
+// Remove 删除任务，正在执行的任务不受影响，任务不存在时返回 false
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[name]; !exists {
+		return false
+	}
+	delete(s.entries, name)
+	s.notify()
+	return true
+}
+
+// Entries 返回所有任务的快照，按下次执行时间排序，不会再执行的排在最后
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e.snapshot())
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if a.Next.IsZero() != b.Next.IsZero() {
+			return iif(a.Next.IsZero(), 1, -1)
+		}
+		return cmp.Or(a.Next.Compare(b.Next), strings.Compare(a.Name, b.Name))
+	})
+	return entries
+}
+
+// Entry 返回指定任务的快照
+func (s *Scheduler) Entry(name string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[name]; ok {
+		return e.snapshot(), true
+	}
+	return Entry{}, false
+}
+
+// Trigger 立即执行一次任务，按任务的重叠策略和执行锁处理，调度器未启动时返回错误
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return errx.Errorf("cron: job %q not found", name)
+	}
+	if s.ctx == nil {
+		return errx.Errorf("cron: scheduler not started")
+	}
+
+	s.log.Info("手动执行任务", "name", name)
+	s.dispatch(s.ctx, e, s.now(), TriggerManual)
+	return nil
+}
+
+// Stats 返回指定任务的执行统计
+func (s *Scheduler) Stats(name string) (Stats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[name]; ok {
+		stats := e.stats
+		stats.Runs = maps.Clone(stats.Runs)
+		return stats, true
+	}
+	return Stats{}, false
+}
+
+// History 返回指定任务最近的执行记录，按时间先后排列
+func (s *Scheduler) History(name string) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[name]; ok {
+		return slices.Clone(e.history)
+	}
+	return nil
+}
+
+// Start 在后台启动调度，ctx 结束后停止调度，并取消正在执行的任务的上下文
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return errx.Errorf("cron: scheduler already started")
+	}
+
+	loopCtx, stop := context.WithCancel(ctx)
+	s.ctx, s.stop, s.done = ctx, stop, make(chan struct{})
+
+	now := s.now()
+	for _, e := range s.entries {
+		s.schedule(e, now)
+	}
+
+	go s.run(loopCtx, s.done)
+	return nil
+}
+
+// schedule 计算任务的下次执行时间，并补执行错过的执行，需持有锁且调度器已启动
+//
+// 启动时读写 Store 会持有锁，Store 应当是本地文件或数据库这类较快的实现
+func (s *Scheduler) schedule(e *entry, now time.Time) {
+	ticks := s.missed(s.ctx, e, now)
+	e.next = e.schedule.Next(now)
+	if len(ticks) > 0 {
+		e.prev = ticks[len(ticks)-1]
+		s.runJob(s.ctx, e, TriggerCatchUp, ticks...)
+	}
+
+	s.persist(s.ctx, []State{{Name: e.name, Prev: e.prev, Next: e.next}})
+}
+
+// Stop 停止调度，并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.ctx, s.stop, s.done = nil, nil, nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		stop()
+		<-done
+	}
+	s.jobs.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	s.log.Debug("调度开始")
+	defer s.log.Debug("调度结束")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		next := s.earliest()
+		s.mu.Unlock()
+
+		if next.IsZero() {
+			timer.Reset(time.Hour * 24 * 365) //没有需要执行的任务，等待新的任务加入
+		} else {
+			timer.Reset(max(next.Sub(s.now()), 0))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+			var states []State
+
+			s.mu.Lock()
+			now, jobCtx := s.now(), s.ctx
+			if jobCtx == nil {
+				s.mu.Unlock()
+				return
+			}
+			for _, e := range s.entries {
+				if e.next.IsZero() || e.next.After(now) {
+					continue
+				}
+				e.prev, e.next = e.next, e.schedule.Next(now)
+				s.dispatch(jobCtx, e, e.prev, TriggerSchedule)
+				states = append(states, State{Name: e.name, Prev: e.prev, Next: e.next})
+			}
+			s.mu.Unlock()
+
+			s.persist(jobCtx, states)
+		}
+	}
+}
+
+// earliest 返回最早的下次执行时间，需持有锁
+func (s *Scheduler) earliest() (next time.Time) {
+	for _, e := range s.entries {
+		if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+			next = e.next
+		}
+	}
+	return
+}
+
+// notify 唤醒调度循环重新计算等待时间
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) now() time.Time { return time.Now().In(s.loc) }
+
+func (e *entry) snapshot() Entry {
+	return Entry{
+		Name: e.name, Spec: e.spec, Overlap: e.overlap,
+		Next: e.next, Prev: e.prev, Running: e.running, Pending: e.pending,
+	}
+}
+
+func iif[T any](c bool, t, f T) T {
+	if c {
+		return t
+	}
+	return f
+}
@@ -0,0 +1,203 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// Overlap 到达执行时间时，上一次执行尚未结束的处理策略
+type Overlap string
+
+const (
+	OverlapAllow  Overlap = "allow"  //允许同时执行(默认)
+	OverlapSkip   Overlap = "skip"   //跳过本次执行
+	OverlapQueue  Overlap = "queue"  //排队，上一次结束后立即执行，最多排队一次，多余的跳过
+	OverlapCancel Overlap = "cancel" //取消上一次执行的上下文，并立即开始本次执行
+)
+
+// 执行结果
+const (
+	OutcomeSuccess  = "success"  //执行成功
+	OutcomeFailed   = "failed"   //执行返回错误或 panic
+	OutcomeSkipped  = "skipped"  //因上一次执行未结束而跳过
+	OutcomeCanceled = "canceled" //因新的执行开始而被取消 (OverlapCancel)
+	OutcomeLocked   = "locked"   //执行权已被其他进程取得 (Lock)
+)
+
+// 触发方式
+const (
+	TriggerSchedule = "schedule" //按计划执行
+	TriggerManual   = "manual"   //手动触发 (Scheduler.Trigger)
+	TriggerQueue    = "queue"    //排队后执行 (OverlapQueue)
+	TriggerCatchUp  = "catch_up" //补执行停机期间错过的执行
+)
+
+// 默认保留的执行记录数
+const historyKeep = 20
+
+// ErrOverlapCanceled 使用 OverlapCancel 策略时，被新的执行取消的上下文的 Cause
+var ErrOverlapCanceled = errors.New("cron: canceled by next run")
+
+// Run 一次执行记录
+type Run struct {
+	Tick     time.Time      `json:"tick"`              //计划执行时间
+	Start    time.Time      `json:"start,omitzero"`    //实际开始时间，跳过时为零值
+	End      time.Time      `json:"end,omitzero"`      //结束时间，跳过时为零值
+	Duration jsonx.Duration `json:"duration,omitzero"` //执行时长
+	Outcome  string         `json:"outcome"`           //执行结果: success, failed, skipped, canceled, locked
+	Trigger  string         `json:"trigger"`           //触发方式: schedule, manual, queue, catch_up
+	Err      string         `json:"err,omitempty"`
+}
+
+// EntryOption 任务选项
+type EntryOption func(*entry)
+
+// WithOverlap 设置上一次执行未结束时的处理策略，默认为 OverlapAllow
+func WithOverlap(overlap Overlap) EntryOption {
+	return func(e *entry) { e.overlap = overlap }
+}
+
+// KeepHistory 设置保留的执行记录数，默认为 20，n<=0 时不保留
+func KeepHistory(n int) EntryOption {
+	return func(e *entry) { e.keep = n }
+}
+
+// dispatch 根据重叠策略执行任务，需持有锁
+func (s *Scheduler) dispatch(ctx context.Context, e *entry, tick time.Time, trigger string) {
+	if e.running > 0 {
+		switch e.overlap {
+		case OverlapSkip:
+			s.skip(e, tick, trigger)
+			return
+		case OverlapQueue:
+			if e.pending {
+				s.skip(e, tick, trigger)
+				return
+			}
+			e.pending, e.pendingTick = true, tick
+			s.log.Debug("任务排队", "name", e.name, "tick", tick)
+			return
+		case OverlapCancel:
+			s.log.Debug("取消上一次执行", "name", e.name, "tick", tick, "running", e.running)
+			for _, cancel := range e.cancels {
+				cancel(ErrOverlapCanceled)
+			}
+		}
+	}
+	s.runJob(ctx, e, trigger, tick)
+}
+
+func (s *Scheduler) skip(e *entry, tick time.Time, trigger string) {
+	s.log.Debug("上一次执行未结束，跳过", "name", e.name, "tick", tick)
+	e.record(Run{Tick: tick, Outcome: OutcomeSkipped, Trigger: trigger})
+}
+
+// runJob 在新的协程中按顺序执行 ticks 对应的任务，需持有锁
+func (s *Scheduler) runJob(ctx context.Context, e *entry, trigger string, ticks ...time.Time) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	e.seq++
+	id := e.seq
+	e.cancels[id] = cancel
+	e.running++
+	s.jobs.Add(1)
+
+	go func() {
+		defer s.jobs.Done()
+
+		for _, tick := range ticks {
+			if jobCtx.Err() != nil {
+				break
+			}
+
+			run := Run{Tick: tick, Start: time.Now(), Trigger: trigger}
+			acquired, err := s.acquire(jobCtx, e, tick)
+			if acquired {
+				err = s.call(jobCtx, e)
+			}
+			run.End = time.Now()
+			run.Duration = jsonx.Duration(run.End.Sub(run.Start))
+
+			switch {
+			case !acquired && err == nil:
+				run.Outcome, run.Start, run.End, run.Duration = OutcomeLocked, time.Time{}, time.Time{}, 0
+			case err == nil:
+				run.Outcome = OutcomeSuccess
+			case errors.Is(context.Cause(jobCtx), ErrOverlapCanceled):
+				run.Outcome, run.Err = OutcomeCanceled, err.Error()
+			default:
+				run.Outcome, run.Err = OutcomeFailed, err.Error()
+				s.log.Warn("任务执行失败", "name", e.name, "tick", tick, "err", err)
+			}
+
+			s.mu.Lock()
+			e.record(run)
+			s.mu.Unlock()
+		}
+		cancel(nil)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(e.cancels, id)
+		e.running--
+
+		if e.pending && e.running == 0 {
+			e.pending = false
+			//调度器已停止或任务已删除时，放弃排队的执行
+			if s.stop != nil && s.entries[e.name] == e {
+				s.runJob(ctx, e, TriggerQueue, e.pendingTick)
+			}
+		}
+	}()
+}
+
+// acquire 取得执行权，没有设置执行锁时总是成功
+func (s *Scheduler) acquire(ctx context.Context, e *entry, tick time.Time) (bool, error) {
+	if s.locker == nil {
+		return true, nil
+	}
+
+	ok, err := s.locker.Acquire(ctx, e.name, tick)
+	if err != nil {
+		return false, errx.Errorf("cron: acquire lock: %w", err)
+	}
+	if !ok {
+		s.log.Debug("执行权已被其他进程取得", "name", e.name, "tick", tick)
+	}
+	return ok, nil
+}
+
+// call 执行任务，将 panic 转换为错误
+func (s *Scheduler) call(ctx context.Context, e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error("任务执行异常", "name", e.name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("cron: panic: %v", r)
+		}
+	}()
+
+	s.log.Debug("执行任务", "name", e.name)
+	return e.job(ctx)
+}
+
+// record 添加执行记录并更新统计，超出保留数时丢弃最早的记录，需持有锁
+func (e *entry) record(run Run) {
+	e.stats.Runs[run.Outcome]++
+	e.stats.Duration += run.Duration
+	if run.Outcome == OutcomeSuccess {
+		e.stats.LastSuccess = run.End
+	}
+
+	if e.keep <= 0 {
+		return
+	}
+	if e.history = append(e.history, run); len(e.history) > e.keep {
+		e.history = e.history[len(e.history)-e.keep:]
+	}
+}
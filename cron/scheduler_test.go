@@ -0,0 +1,192 @@
+package cron
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试中不输出日志
+var discard = Log(slog.New(slog.DiscardHandler))
+
+// waitFor 等待条件成立，超时后失败
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(discard)
+
+	var count atomic.Int32
+	if err := s.Add("@every 1s", "count", func(ctx context.Context) error { count.Add(1); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("@every 1s", "count", func(ctx context.Context) error { return nil }); err == nil {
+		t.Fatal("名称重复未报错")
+	}
+	if err := s.Add("bad spec", "bad", func(ctx context.Context) error { return nil }); err == nil {
+		t.Fatal("无效的表达式未报错")
+	}
+	if err := s.Add("@every 1s", "panic", func(ctx context.Context) error { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Trigger("count"); err == nil {
+		t.Fatal("调度器未启动时 Trigger 未报错")
+	}
+
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if err := s.Start(t.Context()); err == nil {
+		t.Fatal("重复启动未报错")
+	}
+
+	if e, _ := s.Entry("count"); e.Next.IsZero() {
+		t.Fatal("启动后没有下次执行时间")
+	}
+
+	waitFor(t, 5*time.Second, "按计划执行", func() bool {
+		stats, _ := s.Stats("panic")
+		return count.Load() > 0 && stats.Runs[OutcomeFailed] > 0
+	})
+
+	//panic 转换为执行失败
+	history := s.History("panic")
+	if len(history) == 0 || history[0].Err != "cron: panic: boom" || history[0].Trigger != TriggerSchedule {
+		t.Fatalf("panic 执行记录错误: %+v", history)
+	}
+
+	if !s.Remove("count") || s.Remove("count") {
+		t.Fatal("Remove 返回值错误")
+	}
+	if _, ok := s.Entry("count"); ok {
+		t.Fatal("删除的任务仍然存在")
+	}
+	if err := s.Trigger("count"); err == nil {
+		t.Fatal("触发不存在的任务未报错")
+	}
+}
+
+// Stop 等待正在执行的任务结束
+func TestSchedulerStopWait(t *testing.T) {
+	s := NewScheduler(discard)
+
+	var started, finished atomic.Bool
+	if err := s.Add("@every 1h", "slow", func(ctx context.Context) error {
+		started.Store(true)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger("slow"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, "任务开始", started.Load)
+
+	s.Stop()
+	if !finished.Load() {
+		t.Fatal("Stop 未等待正在执行的任务")
+	}
+
+	history := s.History("slow")
+	if len(history) != 1 || history[0].Outcome != OutcomeSuccess || history[0].Trigger != TriggerManual {
+		t.Fatalf("执行记录错误: %+v", history)
+	}
+	if err := s.Trigger("slow"); err == nil {
+		t.Fatal("停止后 Trigger 未报错")
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	hasRun := func(s *Scheduler, match func(Run) bool) bool {
+		for _, r := range s.History("job") {
+			if match(r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		overlap Overlap
+		//上一次执行阻塞期间等待的结果
+		blocked func(s *Scheduler, calls int32) bool
+		//解除阻塞后等待的结果
+		released func(s *Scheduler) bool
+	}{
+		{
+			overlap: OverlapAllow,
+			blocked: func(s *Scheduler, calls int32) bool { e, _ := s.Entry("job"); return calls >= 2 && e.Running >= 1 },
+		},
+		{
+			overlap: OverlapSkip,
+			blocked: func(s *Scheduler, calls int32) bool {
+				return hasRun(s, func(r Run) bool { return r.Outcome == OutcomeSkipped && r.Trigger == TriggerSchedule })
+			},
+		},
+		{
+			overlap: OverlapQueue,
+			blocked: func(s *Scheduler, calls int32) bool { e, _ := s.Entry("job"); return e.Pending && calls == 1 },
+			released: func(s *Scheduler) bool {
+				return hasRun(s, func(r Run) bool { return r.Outcome == OutcomeSuccess && r.Trigger == TriggerQueue })
+			},
+		},
+		{
+			overlap: OverlapCancel,
+			blocked: func(s *Scheduler, calls int32) bool {
+				return calls >= 2 && hasRun(s, func(r Run) bool { return r.Outcome == OutcomeCanceled })
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.overlap), func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			release := make(chan struct{})
+
+			s := NewScheduler(discard)
+			if err := s.Add("@every 1s", "job", func(ctx context.Context) error {
+				if calls.Add(1) > 1 {
+					return nil
+				}
+				//第一次执行阻塞，直到解除或被取消
+				select {
+				case <-release:
+					return nil
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				}
+			}, WithOverlap(tt.overlap)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.Start(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Stop()
+
+			waitFor(t, 5*time.Second, "阻塞期间的结果", func() bool { return tt.blocked(s, calls.Load()) })
+			close(release)
+			if tt.released != nil {
+				waitFor(t, 5*time.Second, "解除阻塞后的结果", func() bool { return tt.released(s) })
+			}
+		})
+	}
+}
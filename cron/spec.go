@@ -26,6 +26,9 @@ type SpecSchedule struct {
 
 	// 覆盖此计划的时区
 	Location *time.Location
+
+	// Quartz 风格的日期规则 (L, W, #)，与 Dom、Dow 中的位集是"或"的关系
+	domRules, dowRules []dayRule
 }
 
 // bounds 提供可接受值的范围（加上名称到值的映射）
@@ -197,8 +200,8 @@ WRAP:
 // 返回值: 如果满足条件返回true，否则返回false
 func dayMatches(s *SpecSchedule, t time.Time) bool {
 	var (
-		domMatch bool = 1<<uint(t.Day())&s.Dom > 0
-		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0
+		domMatch bool = 1<<uint(t.Day())&s.Dom > 0 || matchRules(s.domRules, t)
+		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0 || matchRules(s.dowRules, t)
 	)
 	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
 		return domMatch && dowMatch
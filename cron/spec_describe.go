@@ -0,0 +1,372 @@
+package cron
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// 用于 Describe 的解析器，支持所有字段和扩展
+	describeParser = New(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor | Quartz)
+
+	// 局部变量 dom、dow 会遮盖同名的包级变量
+	domBounds, dowBounds = dom, dow
+)
+
+// Describe 解析计划表达式并返回便于阅读的描述
+//
+// 参数:
+//   - spec: 计划表达式，支持可选的秒字段、描述符和 Quartz 扩展
+//   - lang: 语言，zh 开头的为中文，其他为英文
+//
+// 返回值: 描述文字，如 "every 15 minutes between 09:00 and 18:59 on weekdays"、"工作日 9:00 至 18:59 之间每 15 分钟"
+func Describe(spec, lang string) (string, error) {
+	schedule, err := describeParser.Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	return DescribeSchedule(schedule, lang), nil
+}
+
+// DescribeSchedule 返回执行计划的描述，只支持 SpecSchedule 和 ConstantDelaySchedule，其他类型返回空字符串
+func DescribeSchedule(schedule Schedule, lang string) string {
+	d := describer{zh: strings.HasPrefix(strings.ToLower(lang), "zh")}
+	switch s := schedule.(type) {
+	case ConstantDelaySchedule:
+		return d.pick("every "+s.Delay.String(), "每 "+s.Delay.String())
+	case *SpecSchedule:
+		text := d.spec(s)
+		if s.Location != nil && s.Location != time.Local {
+			text += d.pick(" ("+s.Location.String()+")", "（"+s.Location.String()+"）")
+		}
+		return text
+	}
+	return ""
+}
+
+// field 字段位集分解后的结构
+type field struct {
+	all         bool
+	start, step uint      //step>1 时表示从 start 开始，每 step 一次
+	items       [][2]uint //单个值或连续的范围
+}
+
+// decompose 将位集分解为 全部、步长 或 值和范围的列表
+func decompose(b uint64, r bounds) (f field) {
+	full := getBits(r.min, r.max, 1)
+	if b&starBit > 0 || b&full == full {
+		f.all = true
+		return
+	}
+	b &= full
+
+	if n := bits.OnesCount64(b); n > 2 {
+		start := uint(bits.TrailingZeros64(b))
+		rest := b &^ (1 << start)
+		step := uint(bits.TrailingZeros64(rest)) - start
+		if getBits(start, r.max, step) == b {
+			return field{start: start, step: step}
+		}
+	}
+
+	f.items = runs(b, r)
+	return
+}
+
+// runs 将位集分解为单个值和连续的范围，连续 3 个以上的值合并为范围
+func runs(b uint64, r bounds) (items [][2]uint) {
+	for i := r.min; i <= r.max; i++ {
+		if b&(1<<i) == 0 {
+			continue
+		}
+		j := i
+		for j+1 <= r.max && b&(1<<(j+1)) > 0 {
+			j++
+		}
+		if j-i >= 2 {
+			items = append(items, [2]uint{i, j})
+		} else {
+			for k := i; k <= j; k++ {
+				items = append(items, [2]uint{k, k})
+			}
+		}
+		i = j
+	}
+	return
+}
+
+// single 是否只有一个值
+func (f field) single() bool { return len(f.items) == 1 && f.items[0][0] == f.items[0][1] }
+
+// values 是否只有单个值(没有范围和步长)
+func (f field) values() bool {
+	if f.all || f.step > 1 || len(f.items) == 0 {
+		return false
+	}
+	for _, it := range f.items {
+		if it[0] != it[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// expand 展开值和范围的列表
+func (f field) expand() (vs []uint) {
+	for _, it := range f.items {
+		for v := it[0]; v <= it[1]; v++ {
+			vs = append(vs, v)
+		}
+	}
+	return
+}
+
+func (f field) is(v uint) bool { return f.single() && f.items[0][0] == v }
+
+type describer struct{ zh bool }
+
+func (d describer) pick(en, zh string) string { return iif(d.zh, zh, en) }
+
+func (d describer) spec(s *SpecSchedule) string {
+	var (
+		sec   = decompose(s.Second, seconds)
+		min   = decompose(s.Minute, minutes)
+		hour  = decompose(s.Hour, hours)
+		days  = d.days(s)
+		clock string
+	)
+
+	//固定的时刻: 09:30、09:00 和 18:00，整点时小时的范围也展开为时刻: 08:00、09:00 和 10:00
+	if sec.single() && min.single() && (hour.values() || min.is(0) && !hour.all && hour.step <= 1) {
+		var times []string
+		for _, h := range hour.expand() {
+			times = append(times, d.clock(h, min.items[0][0], sec.items[0][0]))
+		}
+		clock = d.list(times)
+		if d.zh {
+			return d.join(iif(days == "", "每天", days), clock)
+		}
+		return d.join("at "+clock, days)
+	}
+
+	secP, minP, hourP := d.seconds(sec), "", ""
+	minList := false
+
+	switch {
+	case min.all:
+		if secP == "" || !sec.all && sec.step <= 1 {
+			minP = d.pick("every minute", "每分钟") + iif(d.zh && secP != "", "的", "")
+		}
+	case min.step > 1:
+		minP = d.step(min, minutes, "minute", "分钟")
+	case min.is(0) && secP == "" && (hour.all || hour.step > 1):
+		//整点，由小时字段描述
+	default:
+		minP, minList = d.pick(iif(min.single(), "at minute ", "at minutes "), "第 ")+d.items(min, d.num)+d.pick("", " 分钟"), true
+	}
+
+	switch {
+	case hour.all:
+		if minP == "" && secP == "" {
+			hourP = d.pick("every hour", "每小时")
+		} else if minList {
+			hourP = d.pick("of every hour", "每小时的")
+		}
+	case hour.step > 1:
+		hourP = d.step(hour, hours, "hour", "小时") + iif(d.zh && minList, "的", "")
+	case len(hour.items) == 1:
+		h := hour.items[0]
+		hourP = d.pick("between ", "") + d.clock(h[0], 0, 0) + d.pick(" and ", " 至 ") + d.clock(h[1], 59, 0) + d.pick("", " 之间")
+	default:
+		hourP = d.pick("during hours ", "") + d.items(hour, d.num) + d.pick("", " 点")
+	}
+
+	if d.zh {
+		return d.join(days, hourP+minP+secP)
+	}
+	return d.join(secP, minP, hourP, days)
+}
+
+func (d describer) seconds(sec field) string {
+	switch {
+	case sec.all:
+		return d.pick("every second", "每秒")
+	case sec.step > 1:
+		return d.step(sec, seconds, "second", "秒")
+	case sec.is(0):
+		return ""
+	}
+	return d.pick(iif(sec.single(), "at second ", "at seconds "), "第 ") + d.items(sec, d.num) + d.pick("", " 秒")
+}
+
+// days 描述日期部分: 月份、月中天、周中天
+func (d describer) days(s *SpecSchedule) string {
+	var (
+		month = decompose(s.Month, months)
+		dom   = decompose(s.Dom, domBounds)
+		dow   = decompose(s.Dow, dowBounds)
+	)
+	if len(s.domRules) > 0 {
+		dom.all = false
+	}
+	if len(s.dowRules) > 0 {
+		dow.all = false
+	}
+
+	var monthP, domP, dowP string
+	if !month.all {
+		if month.step > 1 {
+			monthP = d.step(month, months, "month", "个月")
+		} else {
+			monthP = d.pick("in ", "") + d.items(month, d.month)
+		}
+	}
+
+	if !dom.all {
+		var parts []string
+		if dom.step > 1 {
+			parts = append(parts, d.step(dom, domBounds, "day", "天"))
+		} else if len(dom.items) > 0 {
+			parts = append(parts, d.pick(iif(dom.single(), "day ", "days "), "")+d.items(dom, d.num)+d.pick("", " 号"))
+		}
+		for _, r := range s.domRules {
+			parts = append(parts, d.rule(r))
+		}
+		if domP = d.list(parts); dom.step <= 1 {
+			domP = d.pick("on "+domP+" of the month", concat(iif(month.all, "每月", ""), domP))
+		}
+	}
+
+	if !dow.all {
+		if dow.step > 1 {
+			dow = field{items: runs(s.Dow, dowBounds)}
+		}
+
+		var parts []string
+		switch {
+		case len(dow.items) == 0:
+		case len(dow.items) == 1 && dow.items[0] == [2]uint{1, 5}:
+			parts = append(parts, d.pick("weekdays", "工作日"))
+		case dow.values() && len(dow.items) == 2 && dow.items[0][0] == 0 && dow.items[1][0] == 6:
+			parts = append(parts, d.pick("weekends", "周末"))
+		default:
+			parts = append(parts, d.items(dow, d.weekday))
+		}
+		for _, r := range s.dowRules {
+			parts = append(parts, d.rule(r))
+		}
+		dowP = d.pick("on ", "") + d.list(parts)
+	}
+
+	if domP != "" && dowP != "" {
+		domP += d.pick(" or ", "或")
+	}
+
+	if d.zh {
+		return concat(monthP+iif(monthP != "" && (domP != "" || dowP != ""), "的", ""), domP+dowP)
+	}
+	return d.join(domP+dowP, monthP)
+}
+
+// rule 描述 Quartz 日期规则
+func (d describer) rule(r dayRule) string {
+	switch r.kind {
+	case ruleLast:
+		if r.n > 0 {
+			return d.pick(fmt.Sprintf("%d %s before the last day", r.n, iif(r.n == 1, "day", "days")), fmt.Sprintf("倒数第 %d 天", r.n+1))
+		}
+		return d.pick("the last day", "最后一天")
+	case ruleLastWeekday:
+		return d.pick("the last weekday", "最后一个工作日")
+	case ruleNearestWeekday:
+		return d.pick(fmt.Sprintf("the weekday nearest day %d", r.n), fmt.Sprintf("离 %d 号最近的工作日", r.n))
+	case ruleLastOf:
+		return d.pick("the last "+d.weekday(uint(r.weekday))+" of the month", "每月最后一个"+d.weekday(uint(r.weekday)))
+	case ruleNth:
+		ordinals := []string{"", "first", "second", "third", "fourth", "fifth"}
+		return d.pick("the "+ordinals[r.n]+" "+d.weekday(uint(r.weekday))+" of the month", fmt.Sprintf("每月第 %d 个%s", r.n, d.weekday(uint(r.weekday))))
+	}
+	return r.String()
+}
+
+// step 描述步长: every 15 minutes, 每 15 分钟，不是从字段最小值开始时附加起点: every 10 days starting on day 5
+func (d describer) step(f field, r bounds, en, zh string) string {
+	s := d.pick(fmt.Sprintf("every %d %ss", f.step, en), fmt.Sprintf("每 %d %s", f.step, zh))
+	if f.start > r.min {
+		switch en {
+		case "day":
+			s += d.pick(fmt.Sprintf(" starting on day %d", f.start), fmt.Sprintf("(从 %d 号开始)", f.start))
+		case "month":
+			s += d.pick(" starting in "+d.month(f.start), "(从"+d.month(f.start)+"开始)")
+		default:
+			s += d.pick(fmt.Sprintf(" starting at %s %d", en, f.start), fmt.Sprintf("(从第 %d %s开始)", f.start, zh))
+		}
+	}
+	return s
+}
+
+// items 描述值和范围的列表: 1, 3 and 5-10
+func (d describer) items(f field, name func(uint) string) string {
+	parts := make([]string, len(f.items))
+	for i, it := range f.items {
+		if it[0] == it[1] {
+			parts[i] = name(it[0])
+		} else {
+			parts[i] = name(it[0]) + d.pick(" through ", "至") + name(it[1])
+		}
+	}
+	return d.list(parts)
+}
+
+func (d describer) list(parts []string) string {
+	if d.zh {
+		return strings.Join(parts, "、")
+	}
+	if n := len(parts); n > 1 {
+		return strings.Join(parts[:n-1], ", ") + " and " + parts[n-1]
+	}
+	return strings.Join(parts, "")
+}
+
+func (d describer) join(parts ...string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		if p != "" {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(p)
+		}
+	}
+	return b.String()
+}
+
+// concat 连接中文文字，数字与文字之间加空格
+func concat(a, b string) string {
+	if a != "" && b != "" && (isDigit(a[len(a)-1]) || isDigit(b[0])) {
+		return a + " " + b
+	}
+	return a + b
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func (d describer) clock(h, m, s uint) string {
+	if s > 0 {
+		return fmt.Sprintf(d.pick("%02d:%02d:%02d", "%d:%02d:%02d"), h, m, s)
+	}
+	return fmt.Sprintf(d.pick("%02d:%02d", "%d:%02d"), h, m)
+}
+
+func (d describer) num(v uint) string { return strconv.Itoa(int(v)) }
+
+func (d describer) month(v uint) string {
+	return d.pick(time.Month(v).String(), fmt.Sprintf("%d月", v))
+}
+
+func (d describer) weekday(v uint) string {
+	return d.pick(time.Weekday(v).String(), "周"+[]string{"日", "一", "二", "三", "四", "五", "六"}[v])
+}
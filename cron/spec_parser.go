@@ -24,8 +24,14 @@ const (
 	Dow                                    // 周中的天字段，默认值为*
 	DowOptional                            // 可选周中的天字段，默认值为*
 	Descriptor                             // 允许使用描述符，如@monthly、@weekly等
+	LastDay                                // 允许月中天使用 L、L-n，周中天使用 dL (月的最后一个星期d)
+	NearestWeekday                         // 允许月中天使用 nW (离n号最近的工作日)，与 LastDay 同时启用时允许 LW
+	NthWeekday                             // 允许周中天使用 d#n (月的第n个星期d)
 )
 
+// Quartz 启用所有 Quartz 风格的日期扩展: L, W, #，? 始终可用，等同于 *
+const Quartz = LastDay | NearestWeekday | NthWeekday
+
 var places = []ParseOption{
 	Second,
 	Minute,
@@ -135,13 +141,20 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		return bits
 	}
 
+	dayField := func(f string, r bounds, parseRule func(string, ParseOption) (dayRule, bool, error)) (bits uint64, rules []dayRule) {
+		if err == nil {
+			bits, rules, err = getDayField(f, r, p.options, parseRule)
+		}
+		return
+	}
+
 	var (
-		second     = field(fields[0], seconds)
-		minute     = field(fields[1], minutes)
-		hour       = field(fields[2], hours)
-		dayOfMonth = field(fields[3], dom)
-		month      = field(fields[4], months)
-		dayOfWeek  = field(fields[5], dow)
+		second               = field(fields[0], seconds)
+		minute               = field(fields[1], minutes)
+		hour                 = field(fields[2], hours)
+		dayOfMonth, domRules = dayField(fields[3], dom, parseDomRule)
+		month                = field(fields[4], months)
+		dayOfWeek, dowRules  = dayField(fields[5], dow, parseDowRule)
 	)
 	if err != nil {
 		return nil, err
@@ -155,6 +168,8 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		Month:    month,
 		Dow:      dayOfWeek,
 		Location: loc,
+		domRules: domRules,
+		dowRules: dowRules,
 	}, nil
 }
 
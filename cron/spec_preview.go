@@ -0,0 +1,51 @@
+package cron
+
+import "time"
+
+// 向前查找上一次执行时间的最大范围，与 SpecSchedule.Next 一致
+const prevLimit = time.Hour * 24 * 366 * 5
+
+// NextN 返回从 from 开始(不含)的 n 个执行时间，计划不再触发时提前结束
+//
+// 参数:
+//   - schedule: 执行计划
+//   - from: 开始时间
+//   - n: 最多返回的数量
+//
+// 返回值: 按时间先后排列的执行时间
+func NextN(schedule Schedule, from time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, max(n, 0))
+	for t := from; len(times) < n; {
+		if t = schedule.Next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// Prev 返回早于 t 的最近一次执行时间，5 年内没有执行时返回零值
+//
+// Schedule 只能向后计算，这里从 t 往前逐步扩大查找范围，再用 Next 向后推算。
+//
+// 参数:
+//   - schedule: 执行计划
+//   - t: 参考时间
+//
+// 返回值: 上一次执行时间
+func Prev(schedule Schedule, t time.Time) time.Time {
+	if s, ok := schedule.(ConstantDelaySchedule); ok {
+		return t.Add(-s.Delay).Truncate(time.Second)
+	}
+
+	for window := time.Second; window <= prevLimit*2; window *= 2 {
+		var prev time.Time
+		for next := schedule.Next(t.Add(-window)); !next.IsZero() && next.Before(t); next = schedule.Next(next) {
+			prev = next
+		}
+		if !prev.IsZero() {
+			return prev
+		}
+	}
+	return time.Time{}
+}
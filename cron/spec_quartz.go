@@ -0,0 +1,192 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dayRule Quartz 风格的日期规则，需要结合具体的年月才能计算出匹配的日期
+type dayRule struct {
+	kind    byte         //ruleLast, ruleLastWeekday, ruleNearestWeekday, ruleLastOf, ruleNth
+	n       int          //L-n 的偏移量，nW 的日期，d#n 的序数
+	weekday time.Weekday //dL, d#n 的星期
+}
+
+const (
+	ruleLast           byte = 'L' //月中天 L, L-n: 月的最后一天(往前 n 天)
+	ruleLastWeekday    byte = 'l' //月中天 LW: 月的最后一个工作日
+	ruleNearestWeekday byte = 'W' //月中天 nW: 离 n 号最近的工作日(不跨月)
+	ruleLastOf         byte = 'D' //周中天 dL: 月的最后一个星期 d
+	ruleNth            byte = '#' //周中天 d#n: 月的第 n 个星期 d
+)
+
+// parseDomRule 解析月中天字段中的 L、L-n、LW、nW，不是扩展语法时返回 ok=false
+func parseDomRule(expr string, options ParseOption) (rule dayRule, ok bool, err error) {
+	upper := strings.ToUpper(expr)
+	switch {
+	case upper == "L":
+		rule = dayRule{kind: ruleLast}
+	case upper == "LW":
+		rule = dayRule{kind: ruleLastWeekday}
+	case strings.HasPrefix(upper, "L-"):
+		n, e := mustParseInt(upper[2:])
+		if e != nil {
+			return rule, true, e
+		}
+		if n > 30 {
+			return rule, true, fmt.Errorf("offset of last day (%d) above maximum (30): %s", n, expr)
+		}
+		rule = dayRule{kind: ruleLast, n: int(n)}
+	case len(upper) > 1 && strings.HasSuffix(upper, "W"):
+		n, e := mustParseInt(upper[:len(upper)-1])
+		if e != nil {
+			return rule, true, e
+		}
+		if n < dom.min || n > dom.max {
+			return rule, true, fmt.Errorf("day of month (%d) out of range [%d, %d]: %s", n, dom.min, dom.max, expr)
+		}
+		rule = dayRule{kind: ruleNearestWeekday, n: int(n)}
+	default:
+		return
+	}
+
+	need := LastDay
+	switch rule.kind {
+	case ruleNearestWeekday:
+		need = NearestWeekday
+	case ruleLastWeekday:
+		need = LastDay | NearestWeekday
+	}
+	if options&need != need {
+		return rule, true, fmt.Errorf("parser does not accept %s in day of month", expr)
+	}
+	return rule, true, nil
+}
+
+// parseDowRule 解析周中天字段中的 dL、d#n，不是扩展语法时返回 ok=false
+func parseDowRule(expr string, options ParseOption) (rule dayRule, ok bool, err error) {
+	var (
+		wd  string
+		n   uint
+		opt ParseOption
+	)
+
+	if i := strings.IndexByte(expr, '#'); i > 0 {
+		if n, err = mustParseInt(expr[i+1:]); err != nil {
+			return rule, true, err
+		}
+		if n < 1 || n > 5 {
+			return rule, true, fmt.Errorf("nth weekday (%d) out of range [1, 5]: %s", n, expr)
+		}
+		wd, rule.kind, rule.n, opt = expr[:i], ruleNth, int(n), NthWeekday
+	} else if len(expr) > 1 && (expr[len(expr)-1] == 'L' || expr[len(expr)-1] == 'l') {
+		wd, rule.kind, opt = expr[:len(expr)-1], ruleLastOf, LastDay
+	} else {
+		return
+	}
+
+	d, err := parseIntOrName(wd, dow.names)
+	if err != nil {
+		return rule, true, err
+	}
+	if d > dow.max {
+		return rule, true, fmt.Errorf("day of week (%d) above maximum (%d): %s", d, dow.max, expr)
+	}
+	if options&opt == 0 {
+		return rule, true, fmt.Errorf("parser does not accept %s in day of week", expr)
+	}
+	rule.weekday = time.Weekday(d)
+	return rule, true, nil
+}
+
+// getDayField 解析月中天或周中天字段，扩展语法解析为规则，其余部分解析为位集
+func getDayField(field string, r bounds, options ParseOption, parseRule func(string, ParseOption) (dayRule, bool, error)) (bits uint64, rules []dayRule, err error) {
+	for expr := range strings.SplitSeq(field, ",") {
+		if expr == "" {
+			continue
+		}
+
+		rule, ok, err := parseRule(expr, options)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ok {
+			rules = append(rules, rule)
+			continue
+		}
+
+		bit, err := getRange(expr, r)
+		if err != nil {
+			return 0, nil, err
+		}
+		bits |= bit
+	}
+
+	//与其他值组合时，星号不再表示"任意"
+	if len(rules) > 0 {
+		bits &^= starBit
+	}
+	return
+}
+
+// match 判断给定时间的日期是否满足规则，t 应已转换到计划的时区
+func (r dayRule) match(t time.Time) bool {
+	day, last := t.Day(), daysIn(t.Year(), t.Month())
+
+	switch r.kind {
+	case ruleLast:
+		return day == last-r.n
+	case ruleLastWeekday:
+		return day == nearestWeekday(t, last, last)
+	case ruleNearestWeekday:
+		return r.n <= last && day == nearestWeekday(t, r.n, last)
+	case ruleLastOf:
+		return t.Weekday() == r.weekday && day > last-7
+	case ruleNth:
+		return t.Weekday() == r.weekday && (day-1)/7+1 == r.n
+	}
+	return false
+}
+
+// String 返回规则的表达式
+func (r dayRule) String() string {
+	switch r.kind {
+	case ruleLast:
+		return iif(r.n > 0, "L-"+strconv.Itoa(r.n), "L")
+	case ruleLastWeekday:
+		return "LW"
+	case ruleNearestWeekday:
+		return strconv.Itoa(r.n) + "W"
+	case ruleLastOf:
+		return strconv.Itoa(int(r.weekday)) + "L"
+	case ruleNth:
+		return strconv.Itoa(int(r.weekday)) + "#" + strconv.Itoa(r.n)
+	}
+	return ""
+}
+
+// nearestWeekday 返回 t 所在月中离 day 号最近的工作日，不跨月
+func nearestWeekday(t time.Time, day, last int) int {
+	switch time.Date(t.Year(), t.Month(), day, 12, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		return iif(day == 1, day+2, day-1)
+	case time.Sunday:
+		return iif(day == last, day-2, day+1)
+	}
+	return day
+}
+
+func matchRules(rules []dayRule, t time.Time) bool {
+	for _, r := range rules {
+		if r.match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 12, 0, 0, 0, time.UTC).Day()
+}
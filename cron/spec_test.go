@@ -0,0 +1,187 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestQuartzNext(t *testing.T) {
+	parser := New(Second | Minute | Hour | Dom | Month | Dow | Quartz)
+
+	tests := []struct {
+		spec string
+		from string
+		want []string
+	}{
+		{"0 0 0 L * ?", "2024-01-15 00:00:00", []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"}},
+		{"0 0 0 L-2 * ?", "2023-02-01 00:00:00", []string{"2023-02-26", "2023-03-29"}},
+		{"0 0 0 LW * ?", "2024-08-01 00:00:00", []string{"2024-08-30", "2024-09-30", "2024-10-31", "2024-11-29"}},
+		{"0 0 0 15W * ?", "2024-06-01 00:00:00", []string{"2024-06-14", "2024-07-15", "2024-08-15", "2024-09-16"}},
+		{"0 0 0 1W * ?", "2024-06-01 00:00:00", []string{"2024-06-03", "2024-07-01"}},
+		{"0 0 0 31W * ?", "2024-08-01 00:00:00", []string{"2024-08-30", "2024-10-31"}},
+		{"0 0 0 ? * 5L", "2024-01-01 00:00:00", []string{"2024-01-26", "2024-02-23", "2024-03-29"}},
+		{"0 0 0 ? * FRI#3", "2024-01-01 00:00:00", []string{"2024-01-19", "2024-02-16", "2024-03-15"}},
+		{"0 0 0 ? * 1#5", "2024-01-01 00:00:00", []string{"2024-01-29", "2024-04-29", "2024-07-29"}},
+		{"0 0 0 1,L * ?", "2024-02-01 00:00:00", []string{"2024-02-29", "2024-03-01", "2024-03-31"}},
+	}
+
+	for _, tt := range tests {
+		s, err := parser.Parse("TZ=UTC " + tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+
+		next, _ := time.ParseInLocation(time.DateTime, tt.from, time.UTC)
+		for _, want := range tt.want {
+			next = s.Next(next)
+			if got := next.Format(time.DateOnly); got != want {
+				t.Errorf("%s: got %s, want %s", tt.spec, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestQuartzDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	//2024-03-10 02:00 夏令时开始，02:30 不存在; 2024-11-03 01:00-02:00 重复
+	s, err := New(Minute | Hour | Dom | Month | Dow | Quartz).Parse("TZ=America/New_York 30 2 ? * 0#2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := s.Next(time.Date(2024, 3, 1, 0, 0, 0, 0, loc))
+	if want := time.Date(2024, 4, 14, 2, 30, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("got %s, want %s", next, want)
+	}
+
+	s, _ = New(Minute | Hour | Dom | Month | Dow | Quartz).Parse("TZ=America/New_York 0 0 L * ?")
+	next = s.Next(time.Date(2024, 10, 15, 0, 0, 0, 0, loc))
+	if want := time.Date(2024, 10, 31, 0, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("got %s, want %s", next, want)
+	}
+	next = s.Next(next)
+	if want := time.Date(2024, 11, 30, 0, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("got %s, want %s", next, want)
+	}
+}
+
+func TestQuartzOptions(t *testing.T) {
+	tests := []struct {
+		options ParseOption
+		spec    string
+		ok      bool
+	}{
+		{Minute | Hour | Dom | Month | Dow, "0 0 L * ?", false},
+		{Minute | Hour | Dom | Month | Dow | LastDay, "0 0 L * ?", true},
+		{Minute | Hour | Dom | Month | Dow | LastDay, "0 0 LW * ?", false},
+		{Minute | Hour | Dom | Month | Dow | NearestWeekday, "0 0 15W * ?", true},
+		{Minute | Hour | Dom | Month | Dow | LastDay, "0 0 ? * 5#3", false},
+		{Minute | Hour | Dom | Month | Dow | NthWeekday, "0 0 ? * 5#3", true},
+		{Minute | Hour | Dom | Month | Dow | Quartz, "0 0 ? * 5#6", false},
+		{Minute | Hour | Dom | Month | Dow | Quartz, "0 0 32W * ?", false},
+		{Minute | Hour | Dom | Month | Dow, "0 0 ? * *", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt.options).Parse(tt.spec); (err == nil) != tt.ok {
+			t.Errorf("%s: ok=%v, err=%v", tt.spec, tt.ok, err)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct{ spec, en, zh string }{
+		{"*/15 9-18 * * 1-5", "every 15 minutes between 09:00 and 18:59 on weekdays", "工作日 9:00 至 18:59 之间每 15 分钟"},
+		{"0 9,12,18 * * *", "at 09:00, 12:00 and 18:00", "每天 9:00、12:00、18:00"},
+		{"30 * * * *", "at minute 30 of every hour", "每小时的第 30 分钟"},
+		{"0 0 1,15 * *", "at 00:00 on days 1 and 15 of the month", "每月 1、15 号 0:00"},
+		{"0 18 L * ?", "at 18:00 on the last day of the month", "每月最后一天 18:00"},
+		{"0 10 ? * 5#3", "at 10:00 on the third Friday of the month", "每月第 3 个周五 10:00"},
+		{"0 8-10 * * *", "at 08:00, 09:00 and 10:00", "每天 8:00、9:00、10:00"},
+		{"0 0 L-1 * ?", "at 00:00 on 1 day before the last day of the month", "每月倒数第 2 天 0:00"},
+		{"0 0 L-2 * ?", "at 00:00 on 2 days before the last day of the month", "每月倒数第 3 天 0:00"},
+		{"0 0 5/10 * *", "at 00:00 every 10 days starting on day 5", "每 10 天(从 5 号开始) 0:00"},
+		{"0 0 1 2/3 *", "at 00:00 on day 1 of the month every 3 months starting in February", "每 3 个月(从2月开始)的 1 号 0:00"},
+		{"0 0 */10 * *", "at 00:00 every 10 days", "每 10 天 0:00"},
+		{"@every 1h30m", "every 1h30m0s", "每 1h30m0s"},
+	}
+
+	for _, tt := range tests {
+		if got, err := Describe(tt.spec, "en"); err != nil || got != tt.en {
+			t.Errorf("%s: got %q, want %q, err=%v", tt.spec, got, tt.en, err)
+		}
+		if got, err := Describe(tt.spec, "zh-CN"); err != nil || got != tt.zh {
+			t.Errorf("%s: got %q, want %q, err=%v", tt.spec, got, tt.zh, err)
+		}
+	}
+}
+
+func TestNextNPrev(t *testing.T) {
+	s, err := New(Minute | Hour | Dom | Month | Dow | Quartz).Parse("TZ=UTC 0 0 L * ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	next := NextN(s, from, 3)
+	if len(next) != 3 || next[0].Day() != 31 || next[1].Day() != 30 || next[2].Day() != 31 {
+		t.Errorf("NextN: %v", next)
+	}
+
+	if prev := Prev(s, from); !prev.Equal(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Prev: %v", prev)
+	}
+	if prev := Prev(s, next[0]); !prev.Equal(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Prev: %v", prev)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	every := Every(time.Hour)
+	from := time.Date(2024, 9, 30, 17, 0, 0, 0, time.UTC) //周一
+
+	w, err := ParseWindow("mon-fri 09:00-18:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := NextN(Within(every, w), from, 3)
+	want := []string{"2024-10-01 09:00", "2024-10-01 10:00", "2024-10-01 11:00"}
+	for i := range want {
+		if i >= len(got) || got[i].Format("2006-01-02 15:04") != want[i] {
+			t.Fatalf("Within: got %v, want %v", got, want)
+		}
+	}
+
+	cal, err := ParseICal(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20241001\r\nDTEND;VALUE=DATE:20241008\r\nSUMMARY:National\r\n  Day\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := ParseStandard("TZ=UTC 0 9 * * *")
+	if next := Exclude(spec, cal).Next(from); next.Format(time.DateOnly) != "2024-10-08" {
+		t.Errorf("Exclude: got %v", next)
+	}
+
+	for range 10 {
+		next := Jitter(spec, time.Minute).Next(from)
+		if base := spec.Next(from); next.Before(base) || !next.Before(base.Add(time.Minute)) {
+			t.Errorf("Jitter: got %v, base %v", next, base)
+		}
+	}
+
+	//调度器从延迟后的执行时间计算下一次，延迟不应累加
+	jitter := Jitter(every, 30*time.Minute)
+	next := from
+	for i := range 24 {
+		next = jitter.Next(next)
+		if base := from.Add(time.Duration(i+1) * time.Hour); next.Before(base) || !next.Before(base.Add(30*time.Minute)) {
+			t.Fatalf("Jitter(Every) drift: #%d got %v, base %v", i+1, next, base)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package cron
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 包装计划向后查找的最大范围，与 SpecSchedule.Next 一致
+const wrapLimit = 5
+
+// Jitter 在计划的每次执行时间上增加 [0, max) 的随机延迟，避免多个副本同时执行
+//
+// max 应小于计划的执行间隔，否则延迟后的时间可能越过下一次执行
+//
+// 从延迟后的执行时间计算下一次时，以上一次未加延迟的计划时间为准，延迟不会累加(如 Every)
+func Jitter(schedule Schedule, max time.Duration) Schedule {
+	return &jitterSchedule{schedule: schedule, max: max}
+}
+
+type jitterSchedule struct {
+	schedule Schedule
+	max      time.Duration
+	base     time.Time //上一次返回的执行时间未加延迟时的计划时间
+	mu       sync.Mutex
+}
+
+func (s *jitterSchedule) Next(t time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.schedule.Next(t)
+	//t 在上一次的计划时间和它的下一次之间(如上一次延迟后的执行时间)，从上一次的计划时间计算
+	if !s.base.IsZero() && !t.Before(s.base) {
+		if n := s.schedule.Next(s.base); n.After(t) {
+			next = n
+		}
+	}
+
+	if s.base = next; next.IsZero() || s.max <= 0 {
+		return next
+	}
+	return next.Add(rand.N(s.max))
+}
+
+// Window 每天允许执行的时间段，时间为计划所在时区的时间
+type Window struct {
+	Start time.Duration  //开始时间，距离零点的时长
+	End   time.Duration  //结束时间(不含)，小于开始时间时表示跨越零点
+	Days  []time.Weekday //开始时间所在的星期，为空时表示每天
+}
+
+// ParseWindow 解析时间段，格式为 "[星期] 开始-结束"
+//
+// 例如 "09:00-18:00"、"mon-fri 09:00-18:00"、"sat,sun 10:00-12:30"、"22:00-06:00"
+func ParseWindow(s string) (w Window, err error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("invalid window: %q", s)
+	}
+
+	if len(fields) == 2 {
+		bits, err := getField(fields[0], dow)
+		if err != nil {
+			return w, fmt.Errorf("invalid window days: %q: %w", s, err)
+		}
+		for d := range 7 {
+			if bits&(1<<d) > 0 {
+				w.Days = append(w.Days, time.Weekday(d))
+			}
+		}
+	}
+
+	start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return w, fmt.Errorf("invalid window: %q", s)
+	}
+	if w.Start, err = parseClock(start); err != nil {
+		return
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return
+	}
+	return
+}
+
+// String 返回时间段的表达式
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	s := clock(w.Start) + "-" + clock(w.End)
+	if len(w.Days) > 0 {
+		days := make([]string, len(w.Days))
+		for i, d := range w.Days {
+			days[i] = strings.ToLower(d.String()[:3])
+		}
+		s = strings.Join(days, ",") + " " + s
+	}
+	return s
+}
+
+func (w Window) MarshalText() ([]byte, error) { return []byte(w.String()), nil }
+
+func (w *Window) UnmarshalText(b []byte) (err error) {
+	*w, err = ParseWindow(string(b))
+	return
+}
+
+// contains 判断 t 是否在时间段内
+func (w Window) contains(t time.Time) bool {
+	offset := clockOf(t)
+	day := t.Weekday()
+	if w.End <= w.Start && offset < w.End {
+		day = (day + 6) % 7 //零点之后的部分属于前一天开始的时间段
+	} else if offset < w.Start || (w.End > w.Start && offset >= w.End) {
+		return false
+	}
+	return len(w.Days) == 0 || slices.Contains(w.Days, day)
+}
+
+// nextStart 返回 t 之后(含)最近的开始时间
+func (w Window) nextStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := range 8 {
+		d := day.AddDate(0, 0, i)
+		if start := atClock(d, w.Start); !start.Before(t) && (len(w.Days) == 0 || slices.Contains(w.Days, d.Weekday())) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// Within 将计划的执行限制在给定的时间段内，时间段外的执行会被跳过
+func Within(schedule Schedule, windows ...Window) Schedule {
+	return &windowSchedule{schedule: schedule, windows: windows}
+}
+
+type windowSchedule struct {
+	schedule Schedule
+	windows  []Window
+}
+
+func (s *windowSchedule) Next(t time.Time) time.Time {
+	if len(s.windows) == 0 {
+		return s.schedule.Next(t)
+	}
+
+	limit := t.AddDate(wrapLimit, 0, 0)
+	for next := s.schedule.Next(t); !next.IsZero() && next.Before(limit); {
+		if s.contains(next) {
+			return next
+		}
+
+		//跳到最近的时间段开始，避免逐个检查时间段外的执行
+		var start time.Time
+		for _, w := range s.windows {
+			if ns := w.nextStart(next); !ns.IsZero() && (start.IsZero() || ns.Before(start)) {
+				start = ns
+			}
+		}
+		if start.IsZero() {
+			return time.Time{}
+		}
+		next = skipTo(s.schedule, next, start)
+	}
+	return time.Time{}
+}
+
+func (s *windowSchedule) contains(t time.Time) bool {
+	for _, w := range s.windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Exclude 跳过日历中排除的日期，日期按计划所在的时区判断
+func Exclude(schedule Schedule, calendar *Calendar) Schedule {
+	return &excludeSchedule{schedule: schedule, calendar: calendar}
+}
+
+type excludeSchedule struct {
+	schedule Schedule
+	calendar *Calendar
+}
+
+func (s *excludeSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(wrapLimit, 0, 0)
+	for next := s.schedule.Next(t); !next.IsZero() && next.Before(limit); {
+		if !s.calendar.Excluded(next) {
+			return next
+		}
+
+		//跳到第二天零点
+		next = skipTo(s.schedule, next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location()))
+	}
+	return time.Time{}
+}
+
+// skipTo 返回 from 之后、不早于 at 的执行时间，固定间隔的计划从 at 开始重新计时
+func skipTo(schedule Schedule, from, at time.Time) time.Time {
+	if !at.After(from) {
+		return schedule.Next(from)
+	}
+	if _, ok := schedule.(ConstantDelaySchedule); ok {
+		return at
+	}
+	return schedule.Next(at.Add(-time.Nanosecond))
+}
+
+// parseClock 解析 HH:MM 或 HH:MM:SS，返回距离零点的时长
+func parseClock(s string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return clockOf(t), nil
+		}
+	}
+	if s == "24:00" {
+		return time.Hour * 24, nil
+	}
+	return 0, fmt.Errorf("invalid clock: %q", s)
+}
+
+// clockOf 返回 t 距离当天零点的时长(按钟表时间，不受夏令时影响)
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// atClock 返回 day 当天指定钟表时间的时刻
+func atClock(day time.Time, d time.Duration) time.Time {
+	h, m, sec := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, sec, 0, day.Location())
+}
@@ -0,0 +1,179 @@
+package cron
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/errx"
+	"github.com/cnk3x/pkg/jsonx"
+)
+
+// State 任务的持久化状态
+type State struct {
+	Name string    `json:"name"`
+	Prev time.Time `json:"prev,omitzero"` //上次执行的计划时间
+	Next time.Time `json:"next,omitzero"` //下次执行的计划时间
+}
+
+// Store 保存任务的执行状态，调度器重启后据此补执行停机期间错过的任务
+//
+// Get 在任务不存在时应返回零值和 nil
+type Store interface {
+	Get(ctx context.Context, name string) (State, error)
+	Put(ctx context.Context, state State) error
+}
+
+// FileStore 以 json 文件保存任务状态
+type FileStore struct {
+	file   string
+	states map[string]State
+	mu     sync.Mutex
+}
+
+// NewFileStore 创建以 json 文件保存任务状态的 Store，文件不存在时会在第一次保存时创建
+func NewFileStore(file string) (*FileStore, error) {
+	s := &FileStore{file: file, states: map[string]State{}}
+	if err := jsonx.UnmarshalFromFile(file, &s.states); err != nil && !os.IsNotExist(err) {
+		return nil, errx.Errorf("cron: load store %s: %w", file, err)
+	}
+	return s, nil
+}
+
+// Get 取得任务状态
+func (s *FileStore) Get(_ context.Context, name string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[name], nil
+}
+
+// Put 保存任务状态，并写入文件，先写入临时文件再替换，中途崩溃不会留下不完整的文件
+func (s *FileStore) Put(_ context.Context, state State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := maps.Clone(s.states)
+	states[state.Name] = state
+	if err := writeJSON(s.file, states); err != nil {
+		return errx.Errorf("cron: save store %s: %w", s.file, err)
+	}
+	s.states = states
+	return nil
+}
+
+// writeJSON 将 v 以 json 格式写入临时文件，成功后替换 file
+func writeJSON(file string, v any) (err error) {
+	data, err := jsonx.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(file)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// CatchUp 调度器启动时，对停机期间错过的执行的处理策略
+type CatchUp string
+
+const (
+	CatchUpSkip CatchUp = "skip" //跳过错过的执行(默认)
+	CatchUpOnce CatchUp = "once" //补执行一次，计划时间为最近一次错过的时间
+	CatchUpAll  CatchUp = "all"  //按顺序补执行所有错过的执行，最多 limit 次
+)
+
+// 补执行的默认最大次数
+const catchUpLimit = 10
+
+// WithCatchUp 设置错过执行的处理策略，需要调度器设置了 Persist
+//
+// 参数:
+//   - policy: 处理策略，默认为 CatchUpSkip
+//   - limit: CatchUpAll 最多补执行的次数，默认为 10
+func WithCatchUp(policy CatchUp, limit ...int) EntryOption {
+	return func(e *entry) {
+		e.catchUp, e.catchUpLimit = policy, catchUpLimit
+		if len(limit) > 0 && limit[0] > 0 {
+			e.catchUpLimit = limit[0]
+		}
+	}
+}
+
+// Persist 设置保存任务执行状态的 Store
+func Persist(store Store) SchedulerOption {
+	return func(s *Scheduler) { s.store = store }
+}
+
+// missed 根据保存的状态计算错过的执行时间，需持有锁
+func (s *Scheduler) missed(ctx context.Context, e *entry, now time.Time) (ticks []time.Time) {
+	if s.store == nil {
+		return
+	}
+
+	state, err := s.store.Get(ctx, e.name)
+	if err != nil {
+		s.log.Warn("读取任务状态失败", "name", e.name, "err", err)
+		return
+	}
+	e.prev = state.Prev
+
+	if state.Next.IsZero() || state.Next.After(now) {
+		return
+	}
+
+	switch e.catchUp {
+	case CatchUpOnce:
+		ticks = append(ticks, Prev(e.schedule, now.Add(time.Nanosecond)))
+	case CatchUpAll:
+		for t := state.Next; !t.IsZero() && !t.After(now) && len(ticks) < e.catchUpLimit; t = e.schedule.Next(t) {
+			ticks = append(ticks, t)
+		}
+	default:
+		s.log.Info("跳过错过的执行", "name", e.name, "next", state.Next)
+		return
+	}
+
+	s.log.Info("补执行错过的任务", "name", e.name, "since", state.Next, "count", len(ticks))
+	return
+}
+
+// persist 保存任务状态
+func (s *Scheduler) persist(ctx context.Context, states []State) {
+	if s.store == nil {
+		return
+	}
+	for _, state := range states {
+		if err := s.store.Put(ctx, state); err != nil {
+			s.log.Warn("保存任务状态失败", "name", state.Name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "state", "cron.json")
+
+	store, err := NewFileStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for i, name := range []string{"a", "b", "a"} {
+		if err = store.Put(t.Context(), State{Name: name, Prev: now, Next: now.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//临时文件替换后不应残留
+	if entries, _ := os.ReadDir(filepath.Dir(file)); len(entries) != 1 {
+		t.Fatalf("目录中有多余的文件: %v", entries)
+	}
+
+	reload, err := NewFileStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := reload.Get(t.Context(), "a"); !state.Next.Equal(now.Add(2 * time.Hour)) {
+		t.Fatalf("重新加载的状态错误: %+v", state)
+	}
+	if state, _ := reload.Get(t.Context(), "none"); !state.Next.IsZero() {
+		t.Fatalf("不存在的任务应返回零值: %+v", state)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	tests := []struct {
+		policy CatchUp
+		limit  int
+		want   int //补执行的次数
+	}{
+		{CatchUpSkip, 0, 0},
+		{CatchUpOnce, 0, 1},
+		{CatchUpAll, 0, 3},
+		{CatchUpAll, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cron.json")
+
+			//停机前的状态: 每小时一次，约 3 小时前应当执行，错过 3 次
+			now := time.Now()
+			seed, err := NewFileStore(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			missed := now.Add(-3*time.Hour + time.Minute).Truncate(time.Second)
+			if err = seed.Put(t.Context(), State{Name: "job", Prev: missed.Add(-time.Hour), Next: missed}); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := NewFileStore(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewScheduler(discard, Persist(store))
+			if err = s.Add("@every 1h", "job", func(ctx context.Context) error { return nil }, WithCatchUp(tt.policy, tt.limit)); err != nil {
+				t.Fatal(err)
+			}
+			if err = s.Start(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			s.Stop() //等待补执行结束
+
+			history := s.History("job")
+			if len(history) != tt.want {
+				t.Fatalf("补执行次数错误: got %d, want %d, %+v", len(history), tt.want, history)
+			}
+			for i, r := range history {
+				if r.Trigger != TriggerCatchUp || r.Outcome != OutcomeSuccess {
+					t.Fatalf("补执行记录错误: %+v", r)
+				}
+				if tt.policy == CatchUpAll && !r.Tick.Equal(missed.Add(time.Duration(i)*time.Hour)) {
+					t.Fatalf("补执行时间错误: %d %v", i, r.Tick)
+				}
+			}
+			if tt.policy == CatchUpOnce && history[0].Tick.Before(now.Add(-time.Hour-time.Second)) {
+				t.Fatalf("只补执行最近一次错过的执行: %v", history[0].Tick)
+			}
+
+			//保存新的下次执行时间
+			state, _ := store.Get(context.Background(), "job")
+			if !state.Next.After(now) {
+				t.Fatalf("未保存下次执行时间: %+v", state)
+			}
+		})
+	}
+}
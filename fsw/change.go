@@ -0,0 +1,107 @@
+package fsw
+
+import (
+	"context"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeKind 文件变化的类型
+type ChangeKind string
+
+const (
+	Created  ChangeKind = "created"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+	Renamed  ChangeKind = "renamed"
+)
+
+// Change 合并后的文件变化，每个路径只出现一次
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Path string     `json:"path"`
+	From string     `json:"from,omitempty"` //Renamed 时的原路径
+}
+
+// ChangeFunc 处理合并后的文件变化
+type ChangeFunc func(ctx context.Context, changes []Change)
+
+// HandleChanges 设置处理函数，事件先经过 Coalesce 合并再交给 handler，与 Handle 同时设置时两者都会调用
+func HandleChanges(handler ChangeFunc) HandlerOption {
+	return func(r *Route) { r.changes = handler }
+}
+
+// Coalesce 将一批事件合并为每个路径一条的变化，按路径第一次出现的顺序返回
+//
+//   - 批次内创建又删除的文件(编辑器的临时文件等)被忽略
+//   - 删除后又创建、或者写入、修改权限的已有文件为 Modified
+//   - Rename 紧跟另一个路径的 Create 视为重命名，原路径是批次内创建的临时文件时新路径为 Created
+//   - 只根据事件无法判断 Create 的路径之前是否存在，覆盖已有文件的重命名(原子保存)为 Created；
+//     HandleChanges 使用 Watcher 记录的已知路径判断，覆盖已有文件时为 Modified
+func Coalesce(events []fsnotify.Event) (changes []Change) { return coalesce(events, nil) }
+
+// coalesce 合并事件，existed 判断路径在批次开始前是否存在，为 nil 时以第一个事件不是 Create 作为判断
+func coalesce(events []fsnotify.Event, existed func(name string) bool) (changes []Change) {
+	type state struct {
+		existed bool   //批次开始前存在
+		exists  bool   //批次结束时存在
+		from    string //由重命名而来的原路径
+		moved   bool   //已重命名为其他路径
+	}
+
+	var (
+		order  []string
+		states = map[string]*state{}
+	)
+
+	get := func(ev fsnotify.Event) *state {
+		s, ok := states[ev.Name]
+		if !ok {
+			s = &state{existed: !ev.Op.Has(fsnotify.Create)}
+			if existed != nil {
+				s.existed = existed(ev.Name)
+			}
+			states[ev.Name] = s
+			order = append(order, ev.Name)
+		}
+		return s
+	}
+
+	for i, ev := range events {
+		s := get(ev)
+		if !ev.Op.Has(fsnotify.Remove | fsnotify.Rename) {
+			s.exists = true
+			continue
+		}
+
+		s.exists = false
+		//inotify 的重命名表现为原路径的 Rename 紧跟新路径的 Create
+		if ev.Op.Has(fsnotify.Rename) && i+1 < len(events) && events[i+1].Op.Has(fsnotify.Create) && events[i+1].Name != ev.Name {
+			to := get(events[i+1])
+			switch {
+			case s.from != "":
+				to.from = s.from //连续重命名，追溯到最初的路径
+			case s.existed:
+				to.from, s.moved = ev.Name, true
+			}
+		} else if s.from != "" {
+			states[s.from].moved = false //重命名后又被删除，原路径视为删除
+		}
+		s.from = ""
+	}
+
+	for _, name := range order {
+		s := states[name]
+		switch {
+		case s.exists && s.from != "":
+			changes = append(changes, Change{Kind: Renamed, Path: name, From: s.from})
+		case s.exists && s.existed:
+			changes = append(changes, Change{Kind: Modified, Path: name})
+		case s.exists:
+			changes = append(changes, Change{Kind: Created, Path: name})
+		case s.existed && !s.moved:
+			changes = append(changes, Change{Kind: Deleted, Path: name})
+		}
+	}
+	return
+}
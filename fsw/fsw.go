@@ -3,11 +3,13 @@ package fsw
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -20,27 +22,40 @@ import (
 const throttleMin = time.Second
 
 type Watcher struct {
-	root     []string
-	exclude  func(string) bool
-	allowOp  fsnotify.Op
-	throttle time.Duration
-
+	root      []string
+	exclude   func(string) bool
+	allowOp   fsnotify.Op
+	throttle  time.Duration
+	recursive bool
+
+	poll     time.Duration
+	compare  string
+	ignore   Rules
+	autoload bool
+
+	roots   []string         //根目录的绝对路径
+	ignores map[string]Rules //目录中加载的忽略文件
+	known   map[string]bool  //已知存在的文件和目录，用于区分新建和覆盖
 	routes  []*Route
 	watches []string
-	fw      *fsnotify.Watcher
+	fw      backend
 	ctx     context.Context
 	mu      sync.Mutex
 }
 
 type Route struct {
-	name     string
-	match    func(string) bool
-	events   fsnotify.Op
-	handler  HandlerFunc
-	throttle time.Duration
+	name      string
+	match     func(string) bool
+	glob      Rules
+	events    fsnotify.Op
+	handler   HandlerFunc
+	changes   ChangeFunc
+	unchanged *unchanged
+	throttle  time.Duration
 
 	timer   *time.Timer
 	payload []fsnotify.Event
+	existed map[string]bool //批次内各路径第一个事件之前是否存在
 
 	mu sync.Mutex
 }
@@ -55,14 +70,40 @@ type Options struct {
 	Exclude  []string
 	Event    string
 	Throttle time.Duration
+
+	// NonRecursive 只监听根目录本身，默认递归监听根目录下的所有子目录
+	//  - 递归监听时，之后新建的目录会自动加入监听，加入前已在其中创建的文件和目录会补发 Create 事件(可能与真实事件重复)
+	//  - 删除或移走的目录会自动取消监听
+	NonRecursive bool
+
+	// Poll 轮询间隔，大于 0 时使用轮询代替 fsnotify，用于 NFS、SMB 等收不到 inotify 事件的文件系统
+	Poll time.Duration
+
+	// Compare 轮询时判断文件变化的方式，逗号分隔，可选 mtime、size、hash，默认为 mtime,size
+	Compare string
+
+	// Ignore gitignore 风格的排除规则，路径相对于所在的根目录，与 Exclude 同时生效
+	Ignore []string
+
+	// LoadIgnore 自动加载监听目录中的 .gitignore 和 .fswignore，规则相对于文件所在的目录，文件修改后重新加载
+	//
+	// 各个文件的规则分别判断，子目录中的 ! 规则不能取消上级目录中的匹配
+	LoadIgnore bool
 }
 
 func New(options Options) *Watcher {
 	return &Watcher{
-		root:     options.Root,
-		exclude:  rex.Compile(options.Exclude...),
-		allowOp:  Op(options.Event),
-		throttle: options.Throttle,
+		root:      options.Root,
+		exclude:   rex.Compile(options.Exclude...),
+		allowOp:   Op(options.Event),
+		throttle:  options.Throttle,
+		recursive: !options.NonRecursive,
+		poll:      options.Poll,
+		compare:   options.Compare,
+		ignore:    CompileRules(options.Ignore...),
+		autoload:  options.LoadIgnore,
+		ignores:   map[string]Rules{},
+		known:     map[string]bool{},
 	}
 }
 
@@ -80,57 +121,138 @@ func (w *Watcher) Run(ctx context.Context) (err error) {
 	slog.Info("watcher run")
 	w.ctx = ctx
 
-	if w.fw, err = fsnotify.NewWatcher(); err != nil {
-		return
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+
+	if w.poll > 0 {
+		p := newPoller(w.poll, w.compare)
+		w.fw, events = p, p.events
+	} else {
+		fw, e := fsnotify.NewWatcher()
+		if e != nil {
+			return e
+		}
+		w.fw, events, errs = fw, fw.Events, fw.Errors
 	}
+	defer w.fw.Close()
+
+	w.roots = lo.FilterMap(w.root, func(root string, _ int) (string, bool) {
+		abs, e := filepath.Abs(root)
+		return abs, e == nil
+	})
 
 	for _, f := range w.root {
-		w.Add(f)
+		w.add(f, !w.recursive)
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("watcher context done: %w", context.Cause(ctx))
-		case err = <-w.fw.Errors:
+		case err = <-errs:
 			return fmt.Errorf("watcher error: %w", err)
-		case payload := <-w.fw.Events:
-			if w.exclude != nil && w.exclude(filepath.Base(payload.Name)) {
+		case payload := <-events:
+			w.mu.Lock()
+			isDir := slices.Contains(w.watches, payload.Name)
+			if !isDir && !payload.Op.Has(fsnotify.Remove|fsnotify.Rename) {
+				isDir = w.isDir(payload.Name)
+			}
+			ignored := w.ignored(payload.Name, isDir)
+			if !ignored && w.autoload && slices.Contains(ignoreFiles, filepath.Base(payload.Name)) {
+				w.loadIgnore(filepath.Dir(payload.Name))
+			}
+			existed := w.known[payload.Name]
+			if !ignored {
+				w.remember(payload, isDir)
+			}
+			w.mu.Unlock()
+
+			if ignored {
 				continue
 			}
 
+			var found []string
 			switch {
 			case payload.Op.Has(fsnotify.Remove | fsnotify.Rename):
 				w.Remove(payload.Name)
-			case payload.Op.Has(fsnotify.Create):
-				w.Add(payload.Name)
+			case payload.Op.Has(fsnotify.Create) && w.recursive:
+				found = w.Add(payload.Name)
 			}
 
-			if w.allowOp != 0 && w.allowOp&payload.Op == 0 {
-				slog.Debug(fmt.Sprintf("event skip op %s %s", payload.Op.String(), payload.Name), "allowOp", w.allowOp.String())
-				continue
+			w.dispatch(payload, isDir, existed)
+
+			//新目录加入监听前已经存在的内容，补发 Create 事件
+			for _, name := range found {
+				w.dispatch(fsnotify.Event{Name: name, Op: fsnotify.Create}, w.isDir(name), false)
+			}
+		}
+	}
+}
+
+// remember 根据事件更新已知存在的路径，需持有锁
+func (w *Watcher) remember(payload fsnotify.Event, isDir bool) {
+	if !payload.Op.Has(fsnotify.Remove | fsnotify.Rename) {
+		w.known[payload.Name] = true
+		return
+	}
+
+	delete(w.known, payload.Name)
+	if isDir {
+		for name := range w.known {
+			if within(payload.Name, name) {
+				delete(w.known, name)
 			}
+		}
+	}
+}
 
-			for _, r := range w.routes {
-				if (r.events == 0 || r.events&payload.Op != 0) && (r.match == nil || r.match(payload.Name)) {
-					r.mu.Lock()
-					r.payload = append(r.payload, payload)
-					r.timer.Reset(max(cmp.Or(r.throttle, w.throttle), throttleMin))
-					r.mu.Unlock()
+// dispatch 将事件分发到匹配的路由，existed 为事件发生之前路径是否存在
+func (w *Watcher) dispatch(payload fsnotify.Event, isDir, existed bool) {
+	if w.allowOp != 0 && w.allowOp&payload.Op == 0 {
+		slog.Debug(fmt.Sprintf("event skip op %s %s", payload.Op.String(), payload.Name), "allowOp", w.allowOp.String())
+		return
+	}
+
+	for _, r := range w.routes {
+		if (r.events == 0 || r.events&payload.Op != 0) && (r.match == nil || r.match(payload.Name)) && (r.glob == nil || r.glob.Match(w.rel(payload.Name), isDir)) {
+			r.mu.Lock()
+			if _, ok := r.existed[payload.Name]; !ok {
+				if r.existed == nil {
+					r.existed = map[string]bool{}
 				}
+				r.existed[payload.Name] = existed
 			}
+			r.payload = append(r.payload, payload)
+			r.timer.Reset(max(cmp.Or(r.throttle, w.throttle), throttleMin))
+			r.mu.Unlock()
 		}
 	}
 }
 
+// Watches 返回正在监听的目录
+func (w *Watcher) Watches() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.watches)
+}
+
 // 递归删除
 func (w *Watcher) Remove(fullPath string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	for dir := range w.ignores {
+		if within(fullPath, dir) {
+			delete(w.ignores, dir)
+		}
+	}
+
 	for _, f := range w.watches {
-		if f == fullPath || strings.HasPrefix(f, fullPath+string(os.PathSeparator)) {
-			if err := w.fw.Remove(f); err != nil {
+		if within(fullPath, f) {
+			//已删除的目录由 fsnotify 自动移除监听
+			if err := w.fw.Remove(f); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
 				slog.Error("watch remove fail", "path", f, "err", err)
 			}
 		}
@@ -138,8 +260,13 @@ func (w *Watcher) Remove(fullPath string) {
 	w.watches = w.fw.WatchList()
 }
 
-// 递归添加
-func (w *Watcher) Add(dir string) {
+// 递归添加，返回目录中已存在的文件和子目录(不含 dir 本身)，只能在 Run 之后调用
+func (w *Watcher) Add(dir string) (found []string) {
+	return w.add(dir, false)
+}
+
+// add 添加监听并记录已存在的路径，only 为 true 时只监听 dir 本身，不进入子目录
+func (w *Watcher) add(dir string, only bool) (found []string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -153,41 +280,74 @@ func (w *Watcher) Add(dir string) {
 		return
 	}
 
+	//先监听目录再读取其中的内容，之后新建的文件由事件通知，之前已存在的由返回值补发
 	if err = filepath.WalkDir(dir, func(fullPath string, d fs.DirEntry, init error) error {
-		if init != nil || !d.IsDir() {
+		if init != nil {
+			if fullPath != dir && errors.Is(init, fs.ErrNotExist) {
+				return nil //遍历期间被删除
+			}
 			return init
 		}
 
-		if w.exclude != nil && w.exclude(d.Name()) {
-			return fs.SkipDir
+		if fullPath != dir {
+			if w.ignored(fullPath, d.IsDir()) {
+				return iif(d.IsDir(), fs.SkipDir, nil)
+			}
+			found = append(found, fullPath)
 		}
+		w.known[fullPath] = true
 
-		if lo.SomeBy(w.watches, func(p string) bool { return p == fullPath }) {
+		if !d.IsDir() {
 			return nil
 		}
+		if only && fullPath != dir {
+			return fs.SkipDir
+		}
 
-		if e := w.fw.Add(fullPath); e != nil {
-			slog.Error("watch add fail", "path", fullPath, "err", e)
-			return nil
+		if w.autoload {
+			w.loadIgnore(fullPath)
 		}
 
-		w.watches = append(w.watches, fullPath)
+		if !slices.Contains(w.watches, fullPath) {
+			if e := w.fw.Add(fullPath); e != nil {
+				slog.Error("watch add fail", "path", fullPath, "err", e)
+				return nil
+			}
+			w.watches = append(w.watches, fullPath)
+		}
 		return nil
 	}); err != nil {
 		slog.Error("watch add fail", "path", dir, "err", err)
 	}
+	return
 }
 
 func (r *Route) Run(ctx context.Context) {
-	if len(r.payload) == 0 || r.handler == nil {
+	r.mu.Lock()
+	events, existed := r.payload, r.existed
+	r.payload, r.existed = nil, nil //处理期间新的事件不能覆盖 events
+	r.mu.Unlock()
+
+	if r.unchanged != nil {
+		events = r.unchanged.filter(ctx, events)
+	}
+
+	if len(events) == 0 {
 		return
 	}
+	if r.handler != nil {
+		r.handler(ctx, events)
+	}
+	if r.changes != nil {
+		if changes := coalesce(events, func(name string) bool { return existed[name] }); len(changes) > 0 {
+			r.changes(ctx, changes)
+		}
+	}
+}
 
-	r.mu.Lock()
-	events := r.payload
-	r.payload = r.payload[:0]
-	r.mu.Unlock()
-	r.handler(ctx, events)
+// Glob 使用 gitignore 风格的规则匹配事件的路径，路径相对于所在的根目录，与 Match 同时设置时都需要匹配
+func Glob(patterns ...string) HandlerOption {
+	return func(r *Route) { r.glob = CompileRules(patterns...) }
 }
 
 func Match(match ...string) HandlerOption           { return func(r *Route) { r.match = rex.Compile(match...) } }
@@ -215,3 +375,69 @@ func Op(s string) fsnotify.Op {
 		}
 	}, 0)
 }
+
+func iif[T any](c bool, t, f T) T {
+	if c {
+		return t
+	}
+	return f
+}
+
+// ignored 判断路径是否被排除，需持有锁
+func (w *Watcher) ignored(fullPath string, isDir bool) bool {
+	if w.exclude != nil && w.exclude(filepath.Base(fullPath)) {
+		return true
+	}
+
+	if w.ignore.Match(w.rel(fullPath), isDir) {
+		return true
+	}
+
+	for dir, rules := range w.ignores {
+		if within(dir, fullPath) {
+			if rel, err := filepath.Rel(dir, fullPath); err == nil && rules.Match(rel, isDir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadIgnore 加载目录中的忽略文件，需持有锁
+func (w *Watcher) loadIgnore(dir string) {
+	var rules Rules
+	for _, name := range ignoreFiles {
+		r, err := LoadRules(filepath.Join(dir, name))
+		if err != nil {
+			slog.Warn("load ignore fail", "path", filepath.Join(dir, name), "err", err)
+		}
+		rules = append(rules, r...)
+	}
+
+	if len(rules) == 0 {
+		delete(w.ignores, dir)
+	} else {
+		w.ignores[dir] = rules
+	}
+}
+
+// rel 返回相对于所在根目录的路径，不在根目录中时返回空
+func (w *Watcher) rel(fullPath string) string {
+	for _, root := range w.roots {
+		if within(root, fullPath) {
+			rel, _ := filepath.Rel(root, fullPath)
+			return rel
+		}
+	}
+	return ""
+}
+
+func (w *Watcher) isDir(name string) bool {
+	stat, err := os.Lstat(name)
+	return err == nil && stat.IsDir()
+}
+
+// within 判断 name 是否为 dir 或者在 dir 中
+func within(dir, name string) bool {
+	return name == dir || strings.HasPrefix(name, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -32,3 +35,374 @@ func TestWatcher(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRecursive(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	fw := New(Options{Root: []string{root}})
+	created := make(chan string, 64)
+	fw.Handle("create", Events("c"), Throttle(time.Millisecond), Handle(func(ctx context.Context, ev []fsnotify.Event) {
+		for _, e := range ev {
+			created <- e.Name
+		}
+	}))
+
+	go fw.Run(ctx)
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+
+	//目录和文件在加入监听之前已经创建
+	deep := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(deep, "f.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for seen := false; !seen; {
+		select {
+		case name := <-created:
+			seen = name == file
+		case <-ctx.Done():
+			t.Fatalf("create event of %s not received", file)
+		}
+	}
+	if !slices.Contains(fw.Watches(), deep) {
+		t.Fatalf("%s not watched: %v", deep, fw.Watches())
+	}
+
+	if err := os.Rename(filepath.Join(root, "a"), filepath.Join(t.TempDir(), "a")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+}
+
+func TestNonRecursive(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	fw := New(Options{Root: []string{root}, NonRecursive: true})
+	created := make(chan string, 64)
+	fw.Handle("create", Events("c"), Throttle(time.Millisecond), Handle(func(ctx context.Context, ev []fsnotify.Event) {
+		for _, e := range ev {
+			created <- e.Name
+		}
+	}))
+
+	go fw.Run(ctx)
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+
+	//子目录中的文件不产生事件，根目录中的文件产生事件
+	for _, file := range []string{filepath.Join(sub, "f.txt"), filepath.Join(root, "f.txt")} {
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case name := <-created:
+		if name != filepath.Join(root, "f.txt") {
+			t.Fatalf("unexpected create event of %s", name)
+		}
+	case <-ctx.Done():
+		t.Fatal("create event not received")
+	}
+	if watches := fw.Watches(); !slices.Equal(watches, []string{root}) {
+		t.Fatalf("only root should be watched: %v", watches)
+	}
+}
+
+func waitFor(t *testing.T, ctx context.Context, cond func() bool) {
+	t.Helper()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			t.Fatal(context.Cause(ctx))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestPoll(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	fw := New(Options{Root: []string{root}, Poll: 20 * time.Millisecond, Compare: "size,hash"})
+	events := make(chan fsnotify.Event, 64)
+	fw.Handle("all", Throttle(time.Millisecond), Handle(func(ctx context.Context, ev []fsnotify.Event) {
+		for _, e := range ev {
+			events <- e
+		}
+	}))
+
+	go fw.Run(ctx)
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+
+	expect := func(name string, op fsnotify.Op) {
+		t.Helper()
+		for {
+			select {
+			case e := <-events:
+				if e.Name == name && e.Op == op {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("%s %s not received", op, name)
+			}
+		}
+	}
+
+	file := filepath.Join(root, "a", "f.txt")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect(file, fsnotify.Create)
+
+	//大小不变，只有内容变化
+	if err := os.WriteFile(file, []byte("2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect(file, fsnotify.Write)
+
+	if err := os.RemoveAll(filepath.Dir(file)); err != nil {
+		t.Fatal(err)
+	}
+	expect(filepath.Dir(file), fsnotify.Remove)
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+}
+
+func TestCoalesce(t *testing.T) {
+	ev := func(op fsnotify.Op, name string) fsnotify.Event { return fsnotify.Event{Name: name, Op: op} }
+
+	for _, c := range []struct {
+		name   string
+		events []fsnotify.Event
+		expect []Change
+	}{
+		{
+			name:   "create and write",
+			events: []fsnotify.Event{ev(fsnotify.Create, "a"), ev(fsnotify.Write, "a"), ev(fsnotify.Chmod, "a")},
+			expect: []Change{{Kind: Created, Path: "a"}},
+		},
+		{
+			name:   "temp file",
+			events: []fsnotify.Event{ev(fsnotify.Create, "a~"), ev(fsnotify.Write, "a~"), ev(fsnotify.Remove, "a~"), ev(fsnotify.Write, "b")},
+			expect: []Change{{Kind: Modified, Path: "b"}},
+		},
+		{
+			name:   "write temp then rename",
+			events: []fsnotify.Event{ev(fsnotify.Create, "a.tmp"), ev(fsnotify.Write, "a.tmp"), ev(fsnotify.Rename, "a.tmp"), ev(fsnotify.Create, "a")},
+			expect: []Change{{Kind: Created, Path: "a"}},
+		},
+		{
+			name: "backup then write",
+			events: []fsnotify.Event{
+				ev(fsnotify.Rename, "a"), ev(fsnotify.Create, "a~"), ev(fsnotify.Create, "a"),
+				ev(fsnotify.Write, "a"), ev(fsnotify.Chmod, "a"), ev(fsnotify.Remove, "a~"),
+			},
+			expect: []Change{{Kind: Modified, Path: "a"}},
+		},
+		{
+			name:   "rename chain",
+			events: []fsnotify.Event{ev(fsnotify.Rename, "a"), ev(fsnotify.Create, "b"), ev(fsnotify.Rename, "b"), ev(fsnotify.Create, "c")},
+			expect: []Change{{Kind: Renamed, Path: "c", From: "a"}},
+		},
+		{
+			name:   "rename then remove",
+			events: []fsnotify.Event{ev(fsnotify.Rename, "a"), ev(fsnotify.Create, "b"), ev(fsnotify.Remove, "b")},
+			expect: []Change{{Kind: Deleted, Path: "a"}},
+		},
+		{
+			name:   "moved out",
+			events: []fsnotify.Event{ev(fsnotify.Rename, "a"), ev(fsnotify.Remove, "b")},
+			expect: []Change{{Kind: Deleted, Path: "a"}, {Kind: Deleted, Path: "b"}},
+		},
+	} {
+		if got := Coalesce(c.events); !slices.Equal(got, c.expect) {
+			t.Errorf("%s: got %v, expect %v", c.name, got, c.expect)
+		}
+	}
+}
+
+// 原子保存(临时文件重命名覆盖已有文件)为 Modified，重命名为新文件为 Created
+func TestAtomicSave(t *testing.T) {
+	for _, nonRecursive := range []bool{false, true} {
+		t.Run(fmt.Sprintf("NonRecursive=%v", nonRecursive), func(t *testing.T) {
+			root := t.TempDir()
+			exists, added := filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")
+			if err := os.WriteFile(exists, []byte("a"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			fw := New(Options{Root: []string{root}, NonRecursive: nonRecursive})
+			got := make(chan Change, 64)
+			fw.Handle("changes", Throttle(time.Millisecond), HandleChanges(func(ctx context.Context, changes []Change) {
+				for _, c := range changes {
+					got <- c
+				}
+			}))
+
+			go fw.Run(ctx)
+			waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+
+			for _, file := range []string{exists, added} {
+				tmp := file + ".tmp"
+				if err := os.WriteFile(tmp, []byte("b"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(tmp, file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changes := map[string]ChangeKind{}
+			for len(changes) < 2 {
+				select {
+				case c := <-got:
+					changes[c.Path] = c.Kind
+				case <-ctx.Done():
+					t.Fatalf("changes not received: %v", changes)
+				}
+			}
+			if changes[exists] != Modified || changes[added] != Created {
+				t.Fatalf("unexpected changes: %v", changes)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules := CompileRules(
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"/build/",
+		"docs/**/*.md",
+		"**/cache",
+		"tmp/",
+		`\!bang`,
+	)
+
+	for _, c := range []struct {
+		rel    string
+		isDir  bool
+		expect bool
+	}{
+		{"a.log", false, true},
+		{"x/y/a.log", false, true},
+		{"x/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/out.bin", false, true},
+		{"src/build/out.bin", false, false},
+		{"docs/a.md", false, true},
+		{"docs/x/y/a.md", false, true},
+		{"src/docs/a.md", false, false},
+		{"a/b/cache", false, true},
+		{"a/tmp/f.txt", false, true},
+		{"tmp", false, false},
+		{"!bang", false, true},
+		{"main.go", false, false},
+	} {
+		if got := rules.Match(c.rel, c.isDir); got != c.expect {
+			t.Errorf("%s (dir=%v): got %v, expect %v", c.rel, c.isDir, got, c.expect)
+		}
+	}
+}
+
+func TestLoadIgnore(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("vendor/\n*.tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "vendor", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fw := New(Options{Root: []string{root}, LoadIgnore: true, Ignore: []string{"/skip"}})
+	created := make(chan string, 64)
+	fw.Handle("go", Glob("*.go"), Throttle(time.Millisecond), Handle(func(ctx context.Context, ev []fsnotify.Event) {
+		for _, e := range ev {
+			created <- e.Name
+		}
+	}))
+
+	go fw.Run(ctx)
+	waitFor(t, ctx, func() bool { return len(fw.Watches()) == 1 })
+
+	for _, name := range []string{"vendor/x/a.go", "b.go.tmp", "skip/c.go", "d.txt", "sub/e.go"} {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect := filepath.Join(root, "sub", "e.go")
+	for {
+		select {
+		case name := <-created:
+			if name == expect {
+				return
+			}
+			t.Fatalf("unexpected event %s", name)
+		case <-ctx.Done():
+			t.Fatalf("event of %s not received", expect)
+		}
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	var r Route
+	SkipUnchanged(4)(&r)
+
+	dir := t.TempDir()
+	file, large := filepath.Join(dir, "a.txt"), filepath.Join(dir, "large.txt")
+	write := func(name, content string) []fsnotify.Event {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return []fsnotify.Event{{Name: name, Op: fsnotify.Write}, {Name: name, Op: fsnotify.Write}}
+	}
+
+	for i, c := range []struct {
+		name, content string
+		expect        int
+	}{
+		{file, "1", 2},
+		{file, "1", 0},
+		{file, "2", 2},
+		{large, "12345", 2},
+		{large, "12345", 2},
+	} {
+		if got := r.unchanged.filter(t.Context(), write(c.name, c.content)); len(got) != c.expect {
+			t.Errorf("#%d: got %d events, expect %d", i, len(got), c.expect)
+		}
+	}
+
+	//删除后重新创建，内容相同也会处理
+	events := []fsnotify.Event{{Name: file, Op: fsnotify.Remove}, {Name: file, Op: fsnotify.Create}, {Name: file, Op: fsnotify.Write}}
+	if got := r.unchanged.filter(t.Context(), events); len(got) != 3 {
+		t.Errorf("recreate: got %v", got)
+	}
+}
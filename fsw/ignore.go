@@ -0,0 +1,150 @@
+package fsw
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 自动加载的忽略文件
+var ignoreFiles = []string{".gitignore", ".fswignore"}
+
+// Rules gitignore 风格的匹配规则，路径为相对于规则所在目录的路径
+//
+//   - 不含 / 的规则匹配任意层级的文件名，含 / 的规则从所在目录开始匹配
+//   - * 和 ? 不匹配 /，** 匹配任意层级的目录
+//   - ! 开头的规则取消之前的匹配，以 / 结尾的规则只匹配目录
+//   - 以最后一条匹配的规则为准，目录匹配后其中的所有文件都匹配
+type Rules []rule
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// CompileRules 编译 gitignore 风格的规则，忽略空行和 # 开头的注释
+func CompileRules(patterns ...string) (rules Rules) {
+	for _, p := range patterns {
+		p = strings.TrimRight(p, " \t\r")
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+
+		var r rule
+		if r.negate = strings.HasPrefix(p, "!"); r.negate {
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+			p = p[1:]
+		}
+
+		if r.dirOnly = strings.HasSuffix(p, "/"); r.dirOnly {
+			p = strings.TrimRight(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+
+		anchored := strings.Contains(p, "/")
+		p = strings.TrimPrefix(p, "/")
+
+		expr := globRegexp(p)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		r.re = regexp.MustCompile("^" + expr + "$")
+		rules = append(rules, r)
+	}
+	return
+}
+
+// LoadRules 从文件加载规则，文件不存在时返回 nil
+func LoadRules(file string) (Rules, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		lines = append(lines, scanner.Text())
+	}
+	return CompileRules(lines...), nil
+}
+
+// Match 判断相对路径 rel 或其所在的目录是否匹配规则
+func (rules Rules) Match(rel string, isDir bool) bool {
+	if len(rules) == 0 {
+		return false
+	}
+
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	if rel == "" || rel == "." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+
+	for i := range len(rel) {
+		if rel[i] == '/' && rules.match(rel[:i], true) {
+			return true
+		}
+	}
+	return rules.match(rel, isDir)
+}
+
+func (rules Rules) match(rel string, isDir bool) (matched bool) {
+	for _, r := range rules {
+		if (!r.dirOnly || isDir) && r.re.MatchString(rel) {
+			matched = !r.negate
+		}
+	}
+	return
+}
+
+// globRegexp 将 glob 转换为正则表达式
+func globRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") && (i == 0 || glob[i-1] == '/') && (i+2 == len(glob) || glob[i+2] == '/') {
+				if i+2 == len(glob) {
+					sb.WriteString(".*") //a/** 匹配 a 中的所有内容
+				} else {
+					sb.WriteString("(?:.*/)?") //**/b 和 a/**/b 匹配任意层级
+					i++
+				}
+				i++
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,232 @@
+package fsw
+
+import (
+	"context"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/pkg/filex"
+	"github.com/fsnotify/fsnotify"
+)
+
+// backend 监听的实现，fsnotify 或轮询，只监听目录本身及其直接包含的文件
+type backend interface {
+	Add(name string) error
+	Remove(name string) error
+	WatchList() []string
+	Close() error
+}
+
+// 轮询时判断文件变化的方式
+const (
+	CompareMtime = "mtime" //修改时间
+	CompareSize  = "size"  //文件大小
+	CompareHash  = "hash"  //文件内容的 md5，每次轮询都会读取全部文件，只适合文件较少的目录
+)
+
+// poller 轮询实现，定时读取监听的目录并与上一次的结果比较，产生与 fsnotify 相同的事件
+//
+// 无法区分重命名和删除，重命名会产生旧名称的 Remove 和新名称的 Create 事件
+type poller struct {
+	interval time.Duration
+	compare  []string
+
+	dirs   map[string]map[string]fileState
+	events chan fsnotify.Event
+	done   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+type fileState struct {
+	mode  fs.FileMode
+	mtime time.Time
+	size  int64
+	hash  string
+}
+
+// newPoller 创建轮询实现
+//
+// 参数:
+//   - interval: 轮询间隔
+//   - compare: 判断文件变化的方式，逗号分隔，可选 mtime、size、hash，默认为 mtime,size
+func newPoller(interval time.Duration, compare string) *poller {
+	p := &poller{
+		interval: interval,
+		compare:  strings.FieldsFunc(compare, func(r rune) bool { return r == ',' || r == ' ' }),
+		dirs:     map[string]map[string]fileState{},
+		events:   make(chan fsnotify.Event),
+		done:     make(chan struct{}),
+	}
+	if len(p.compare) == 0 {
+		p.compare = []string{CompareMtime, CompareSize}
+	}
+
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Add 监听目录，以当前的内容作为比较的基准
+func (p *poller) Add(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.dirs[name]; ok {
+		return nil
+	}
+
+	files, err := p.scan(name)
+	if err != nil {
+		return err
+	}
+	p.dirs[name] = files
+	return nil
+}
+
+// Remove 取消监听
+func (p *poller) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.dirs[name]; !ok {
+		return fsnotify.ErrNonExistentWatch
+	}
+	delete(p.dirs, name)
+	return nil
+}
+
+// WatchList 返回正在监听的目录
+func (p *poller) WatchList() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Collect(maps.Keys(p.dirs))
+}
+
+// Close 停止轮询
+func (p *poller) Close() error {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	p.wg.Wait()
+	return nil
+}
+
+func (p *poller) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			for _, ev := range p.poll() {
+				select {
+				case p.events <- ev:
+				case <-p.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// poll 读取所有监听的目录，返回与上一次相比的变化
+func (p *poller) poll() (events []fsnotify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, dir := range slices.Sorted(maps.Keys(p.dirs)) {
+		files, err := p.scan(dir)
+		if err != nil {
+			//目录已被删除，与 fsnotify 一致，产生目录自身的 Remove 事件并取消监听
+			if os.IsNotExist(err) {
+				delete(p.dirs, dir)
+				events = append(events, fsnotify.Event{Name: dir, Op: fsnotify.Remove})
+			}
+			continue
+		}
+
+		last := p.dirs[dir]
+		for _, name := range slices.Sorted(maps.Keys(last)) {
+			if _, ok := files[name]; !ok {
+				events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Remove})
+			}
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			prev, ok := last[name]
+			switch cur := files[name]; {
+			case !ok:
+				events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Create})
+			case cur.mode.Type() != prev.mode.Type():
+				events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Remove}, fsnotify.Event{Name: name, Op: fsnotify.Create})
+			case p.changed(prev, cur):
+				events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Write})
+			case cur.mode != prev.mode:
+				events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Chmod})
+			}
+		}
+		p.dirs[dir] = files
+	}
+	return
+}
+
+// changed 按比较方式判断文件内容是否变化，目录的变化由其中文件的事件体现
+func (p *poller) changed(prev, cur fileState) bool {
+	if cur.mode.IsDir() {
+		return false
+	}
+	for _, c := range p.compare {
+		switch c {
+		case CompareMtime:
+			if !cur.mtime.Equal(prev.mtime) {
+				return true
+			}
+		case CompareSize:
+			if cur.size != prev.size {
+				return true
+			}
+		case CompareHash:
+			if cur.hash != prev.hash {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scan 读取目录直接包含的文件
+func (p *poller) scan(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue //读取期间被删除
+		}
+
+		name := filepath.Join(dir, entry.Name())
+		st := fileState{mode: info.Mode(), mtime: info.ModTime(), size: info.Size()}
+		if info.Mode().IsRegular() && slices.Contains(p.compare, CompareHash) {
+			st.hash, _ = filex.CalcSum(context.Background(), name, "md5")
+		}
+		files[name] = st
+	}
+	return files, nil
+}
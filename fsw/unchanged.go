@@ -0,0 +1,79 @@
+package fsw
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/cnk3x/pkg/filex"
+	"github.com/fsnotify/fsnotify"
+)
+
+// 计算内容哈希的默认文件大小上限
+const unchangedMaxSize = 64 << 20
+
+// SkipUnchanged 丢弃内容没有变化的 Write 事件，与上一次交给处理函数时的内容哈希比较
+//
+//   - 路径第一次出现时没有可比较的内容，事件总会被处理
+//   - 超过 maxSize 的文件不计算哈希，事件总会被处理，maxSize <= 0 时为 64MB
+func SkipUnchanged(maxSize int64) HandlerOption {
+	return func(r *Route) {
+		r.unchanged = &unchanged{maxSize: maxSize, hashes: map[string]string{}}
+		if r.unchanged.maxSize <= 0 {
+			r.unchanged.maxSize = unchangedMaxSize
+		}
+	}
+}
+
+// unchanged 记录已处理的文件内容哈希
+type unchanged struct {
+	maxSize int64
+	hashes  map[string]string
+	mu      sync.Mutex
+}
+
+// filter 丢弃内容没有变化的 Write 事件，并更新记录的哈希
+func (u *unchanged) filter(ctx context.Context, events []fsnotify.Event) (result []fsnotify.Event) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	//同一批事件中每个路径只计算一次
+	changed := map[string]bool{}
+	for _, ev := range events {
+		switch {
+		case ev.Op.Has(fsnotify.Remove | fsnotify.Rename):
+			delete(u.hashes, ev.Name)
+			delete(changed, ev.Name)
+		case ev.Op.Has(fsnotify.Create | fsnotify.Write):
+			c, ok := changed[ev.Name]
+			if !ok {
+				c = u.update(ctx, ev.Name)
+				changed[ev.Name] = c
+			}
+			if !c && !ev.Op.Has(fsnotify.Create) {
+				continue
+			}
+		}
+		result = append(result, ev)
+	}
+	return
+}
+
+// update 计算文件的内容哈希，返回与记录的是否不同，无法计算时视为不同
+func (u *unchanged) update(ctx context.Context, name string) bool {
+	stat, err := os.Stat(name)
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() > u.maxSize {
+		delete(u.hashes, name)
+		return true
+	}
+
+	digest, err := filex.CalcSum(ctx, name, "md5")
+	if err != nil {
+		delete(u.hashes, name)
+		return true
+	}
+
+	last, ok := u.hashes[name]
+	u.hashes[name] = digest
+	return !ok || last != digest
+}
@@ -0,0 +1,14 @@
+module github.com/cnk3x/pkg/gormx/cronstore
+
+go 1.25.3
+
+require (
+	github.com/cnk3x/pkg v0.1.0
+	gorm.io/gorm v1.31.1
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.29.0 // indirect
+)
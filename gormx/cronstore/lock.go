@@ -0,0 +1,61 @@
+package cronstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/cnk3x/pkg/cron"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CronLock 执行锁表，表名为 cron_locks (加上 gormx.Config.TablePrefix)，每个任务一行
+type CronLock struct {
+	Name      string `gorm:"primaryKey;size:191"`
+	Tick      time.Time
+	Owner     string `gorm:"size:255"`
+	ExpiresAt time.Time
+}
+
+// Locker 使用数据库行租约实现的执行锁，实现了 cron.Locker
+type Locker struct {
+	db    *gorm.DB
+	lease time.Duration
+	owner string
+}
+
+var _ cron.Locker = (*Locker)(nil)
+
+// NewLocker 创建数据库执行锁，并自动迁移表结构
+//
+// 参数:
+//   - db: 数据库
+//   - lease: 租约时长，使用 cron.Jitter 时各副本的计划时间不同，应设置为不小于抖动的最大值
+func NewLocker(db *gorm.DB, lease time.Duration) (*Locker, error) {
+	if err := db.AutoMigrate(&CronLock{}); err != nil {
+		return nil, err
+	}
+	return &Locker{db: db, lease: lease, owner: cron.Owner()}, nil
+}
+
+// Acquire 尝试取得任务在 tick 的执行权
+//
+// 先尝试插入新行，已存在时以条件更新抢占: 上次的计划时间早于 tick 且租约已过期，影响行数为 1 的进程取得执行权
+func (l *Locker) Acquire(ctx context.Context, name string, tick time.Time) (bool, error) {
+	now := time.Now()
+	row := CronLock{Name: name, Tick: tick, Owner: l.owner, ExpiresAt: now.Add(l.lease)}
+	db := l.db.WithContext(ctx)
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = db.Model(&CronLock{}).
+		Where("name = ? AND tick < ? AND expires_at <= ?", name, tick, now).
+		Updates(map[string]any{"tick": tick, "owner": l.owner, "expires_at": row.ExpiresAt})
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,55 @@
+package cronstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cnk3x/pkg/cron"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CronJob 任务状态表，表名为 cron_jobs (加上 gormx.Config.TablePrefix)
+type CronJob struct {
+	Name      string    `gorm:"primaryKey;size:191"`
+	Prev      time.Time `gorm:"index"`
+	Next      time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// Store 使用数据库保存任务状态，实现了 cron.Store
+type Store struct {
+	db *gorm.DB
+}
+
+var _ cron.Store = (*Store)(nil)
+
+// New 创建使用数据库保存任务状态的 Store，并自动迁移表结构
+func New(db *gorm.DB) (*Store, error) {
+	if err := db.AutoMigrate(&CronJob{}); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Get 取得任务状态，不存在时返回零值
+func (s *Store) Get(ctx context.Context, name string) (state cron.State, err error) {
+	var job CronJob
+	if err = s.db.WithContext(ctx).Where("name = ?", name).Take(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		return
+	}
+	return cron.State{Name: job.Name, Prev: job.Prev, Next: job.Next}, nil
+}
+
+// Put 保存任务状态
+func (s *Store) Put(ctx context.Context, state cron.State) error {
+	job := CronJob{Name: state.Name, Prev: state.Prev, Next: state.Next}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"prev", "next", "updated_at"}),
+	}).Create(&job).Error
+}